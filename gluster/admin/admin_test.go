@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+)
+
+type fakeDriver struct {
+	volumes map[string]*driver.VolumeInfo
+	calls   []string
+}
+
+func (f *fakeDriver) Volumes() ([]driver.VolumeInfo, error) {
+	var infos []driver.VolumeInfo
+	for _, i := range f.volumes {
+		infos = append(infos, *i)
+	}
+	return infos, nil
+}
+
+func (f *fakeDriver) Volume(name string) (*driver.VolumeInfo, error) {
+	i, ok := f.volumes[name]
+	if !ok {
+		return nil, fmt.Errorf("volume %s not found", name)
+	}
+	return i, nil
+}
+
+func (f *fakeDriver) RemoveVolume(name string, force bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("rm %s %v", name, force))
+	delete(f.volumes, name)
+	return nil
+}
+
+func (f *fakeDriver) Prune() ([]string, error) {
+	return []string{"unused"}, nil
+}
+
+func (f *fakeDriver) ForceUnmount(name string) error {
+	f.calls = append(f.calls, "unmount "+name)
+	return nil
+}
+
+func (f *fakeDriver) Remount(name string) error {
+	return fmt.Errorf("unable to mount %s", name)
+}
+
+func (f *fakeDriver) Reload() error {
+	f.calls = append(f.calls, "reload")
+	return nil
+}
+
+func (f *fakeDriver) Reconcile() ([]string, error) {
+	return []string{"mounted /mnt/test"}, nil
+}
+
+func (f *fakeDriver) Export() (*driver.Export, error) {
+	return &driver.Export{Version: driver.ExportVersion, Volumes: []driver.ExportedVolume{{Name: "test", VolumeURI: "node:test"}}}, nil
+}
+
+func (f *fakeDriver) Import(e *driver.Export, conflict string, dryRun bool) ([]driver.ImportResult, error) {
+	f.calls = append(f.calls, fmt.Sprintf("import %d %s %v", len(e.Volumes), conflict, dryRun))
+	return []driver.ImportResult{{Name: e.Volumes[0].Name, Action: "created"}}, nil
+}
+
+func (f *fakeDriver) GC(dryRun bool) (*driver.GCResult, error) {
+	return &driver.GCResult{Removed: []string{"/mnt/orphan"}}, nil
+}
+
+type reloadableDriver struct {
+	*fakeDriver
+}
+
+func (r reloadableDriver) ReloadConfig() ([]string, error) {
+	return []string{"basedir"}, nil
+}
+
+func TestAdminAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "run", "admin.sock")
+
+	l, err := Listen(path, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket to be only accessible by owner, got %v (%v)", fi.Mode(), err)
+	}
+	if files, err := ioutil.ReadDir(filepath.Dir(path)); err != nil || len(files) != 1 {
+		t.Errorf("Expected only the socket in its folder, got %v (%v)", files, err)
+	}
+	f := &fakeDriver{volumes: map[string]*driver.VolumeInfo{
+		"test": {Name: "test", VolumeURI: "node:test", Connections: 1, Mounted: true},
+	}}
+	go http.Serve(l, NewHandler(f))
+
+	c := NewClient(path)
+	if err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	infos, err := c.Volumes()
+	if err != nil || len(infos) != 1 || infos[0].VolumeURI != "node:test" || !infos[0].Mounted {
+		t.Errorf("Unexpected volumes %v (%v)", infos, err)
+	}
+	if _, err := c.Volume("other"); err == nil || err.Error() != "volume other not found" {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if err := c.Remount("test"); err == nil || err.Error() != "unable to mount test" {
+		t.Errorf("Expected remount error to be forwarded, got %v", err)
+	}
+	if removed, err := c.Prune(); err != nil || !reflect.DeepEqual(removed, []string{"unused"}) {
+		t.Errorf("Unexpected prune result %v (%v)", removed, err)
+	}
+	if actions, err := c.Reconcile(); err != nil || len(actions) != 1 {
+		t.Errorf("Unexpected reconcile result %v (%v)", actions, err)
+	}
+	if err := c.ForceUnmount("test"); err != nil {
+		t.Error(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Error(err)
+	}
+	if err := c.RemoveVolume("test", true); err != nil {
+		t.Error(err)
+	}
+	e, err := c.Export()
+	if err != nil || len(e.Volumes) != 1 {
+		t.Fatalf("Unexpected export %v (%v)", e, err)
+	}
+	if results, err := c.Import(e, driver.ConflictRename, true); err != nil || len(results) != 1 || results[0].Action != "created" {
+		t.Errorf("Unexpected import result %v (%v)", results, err)
+	}
+	if !reflect.DeepEqual(f.calls, []string{"unmount test", "reload", "rm test true", "import 1 rename true"}) {
+		t.Errorf("Unexpected driver calls %v", f.calls)
+	}
+	if _, err := c.ReloadConfig(); err == nil {
+		t.Error("Expected config reload to fail if not supported by driver")
+	}
+	if res, err := c.GC(true); err != nil || len(res.Removed) != 1 {
+		t.Errorf("Unexpected gc result %v (%v)", res, err)
+	}
+	var buf bytes.Buffer
+	if err := c.Goroutines(&buf); err != nil || !strings.Contains(buf.String(), "goroutine") {
+		t.Errorf("Expected goroutine dump, got %s (%v)", buf.String(), err)
+	}
+
+	rpath := filepath.Join(dir, "reload.sock")
+	rl, err := Listen(rpath, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	go http.Serve(rl, NewHandler(reloadableDriver{f}))
+	if restart, err := NewClient(rpath).ReloadConfig(); err != nil || !reflect.DeepEqual(restart, []string{"basedir"}) {
+		t.Errorf("Unexpected reload result %v (%v)", restart, err)
+	}
+
+	if err := NewClient(filepath.Join(dir, "missing.sock")).Ping(); err == nil {
+		t.Error("Expected ping to fail without daemon")
+	}
+}
@@ -0,0 +1,157 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+)
+
+//Client of the admin API of a running daemon
+type Client struct {
+	http *http.Client
+}
+
+//NewClient return a client talking to the daemon listening on the unix socket path
+func NewClient(path string) *Client {
+	return &Client{http: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		},
+		Timeout: 5 * time.Minute, //Mounting can be slow
+	}}
+}
+
+//Ping return nil if the daemon is reachable
+func (c *Client) Ping() error {
+	return c.call(http.MethodGet, "/volumes", nil, nil)
+}
+
+//Volumes return the state of all volumes
+func (c *Client) Volumes() ([]driver.VolumeInfo, error) {
+	var infos []driver.VolumeInfo
+	return infos, c.call(http.MethodGet, "/volumes", nil, &infos)
+}
+
+//Volume return the state of a volume
+func (c *Client) Volume(name string) (*driver.VolumeInfo, error) {
+	var info driver.VolumeInfo
+	if err := c.call(http.MethodGet, "/volumes/"+url.PathEscape(name), nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+//RemoveVolume remove a volume
+func (c *Client) RemoveVolume(name string, force bool) error {
+	return c.call(http.MethodDelete, fmt.Sprintf("/volumes/%s?force=%v", url.PathEscape(name), force), nil, nil)
+}
+
+//Prune remove all unused volumes
+func (c *Client) Prune() ([]string, error) {
+	var removed []string
+	return removed, c.call(http.MethodPost, "/prune", nil, &removed)
+}
+
+//ForceUnmount unmount the mountpoint of a volume even if used
+func (c *Client) ForceUnmount(name string) error {
+	return c.call(http.MethodPost, "/volumes/"+url.PathEscape(name)+"/unmount", nil, nil)
+}
+
+//Remount mount again the mountpoint of a volume
+func (c *Client) Remount(name string) error {
+	return c.call(http.MethodPost, "/volumes/"+url.PathEscape(name)+"/remount", nil, nil)
+}
+
+//Reload make the daemon read again its persistence file
+func (c *Client) Reload() error {
+	return c.call(http.MethodPost, "/reload", nil, nil)
+}
+
+//Reconcile align mountpoints with connections and return actions done
+func (c *Client) Reconcile() ([]string, error) {
+	var actions []string
+	return actions, c.call(http.MethodPost, "/reconcile", nil, &actions)
+}
+
+//Export return the definitions of all volumes
+func (c *Client) Export() (*driver.Export, error) {
+	var e driver.Export
+	if err := c.call(http.MethodGet, "/export", nil, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+//Import create the volumes of an export
+func (c *Client) Import(e *driver.Export, conflict string, dryRun bool) ([]driver.ImportResult, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var results []driver.ImportResult
+	query := url.Values{"conflict": {conflict}, "dry-run": {strconv.FormatBool(dryRun)}}
+	return results, c.call(http.MethodPost, "/import?"+query.Encode(), bytes.NewReader(b), &results)
+}
+
+//ReloadConfig make the daemon read again its config file and return the settings needing a restart
+func (c *Client) ReloadConfig() ([]string, error) {
+	var res ReloadResponse
+	return res.RestartNeeded, c.call(http.MethodPost, "/config/reload", nil, &res)
+}
+
+//GC remove empty unused directories under the root of the daemon
+func (c *Client) GC(dryRun bool) (*driver.GCResult, error) {
+	var res driver.GCResult
+	if err := c.call(http.MethodPost, "/gc?dry-run="+strconv.FormatBool(dryRun), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+//Goroutines write the stack of all goroutines of the daemon
+func (c *Client) Goroutines(w io.Writer) error {
+	resp, err := c.http.Get("http://admin/debug/goroutines")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (c *Client) call(method, path string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, "http://admin"+path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e errorResponse
+		b, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(b, &e) != nil || e.Err == "" {
+			e.Err = fmt.Sprintf("unexpected response %s", resp.Status)
+		}
+		return fmt.Errorf("%s", e.Err)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
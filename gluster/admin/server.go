@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+)
+
+//Driver needed interface to administrate volumes
+type Driver interface {
+	Volumes() ([]driver.VolumeInfo, error)
+	Volume(name string) (*driver.VolumeInfo, error)
+	RemoveVolume(name string, force bool) error
+	Prune() ([]string, error)
+	ForceUnmount(name string) error
+	Remount(name string) error
+	Reload() error
+	Reconcile() ([]string, error)
+	Export() (*driver.Export, error)
+	Import(e *driver.Export, conflict string, dryRun bool) ([]driver.ImportResult, error)
+	GC(dryRun bool) (*driver.GCResult, error)
+}
+
+//ConfigReloader optional interface of a driver able to reload the daemon config file
+type ConfigReloader interface {
+	ReloadConfig() ([]string, error)
+}
+
+//ReloadResponse settings of the config file that need a restart to be applied
+type ReloadResponse struct {
+	RestartNeeded []string `json:"restart_needed"`
+}
+
+type errorResponse struct {
+	Err string `json:"error"`
+}
+
+//NewHandler return the http handler of the admin API
+func NewHandler(d Driver) http.Handler {
+	return &handler{d: d}
+}
+
+type handler struct {
+	d Driver
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("Admin API: %s %s", r.Method, r.URL.Path)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/volumes":
+		infos, err := h.d.Volumes()
+		reply(w, infos, err)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "volumes":
+		info, err := h.d.Volume(parts[1])
+		reply(w, info, err)
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "volumes":
+		reply(w, nil, h.d.RemoveVolume(parts[1], r.URL.Query().Get("force") == "true"))
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "volumes" && parts[2] == "unmount":
+		reply(w, nil, h.d.ForceUnmount(parts[1]))
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "volumes" && parts[2] == "remount":
+		reply(w, nil, h.d.Remount(parts[1]))
+	case r.Method == http.MethodPost && r.URL.Path == "/prune":
+		removed, err := h.d.Prune()
+		reply(w, removed, err)
+	case r.Method == http.MethodPost && r.URL.Path == "/reload":
+		reply(w, nil, h.d.Reload())
+	case r.Method == http.MethodPost && r.URL.Path == "/reconcile":
+		actions, err := h.d.Reconcile()
+		reply(w, actions, err)
+	case r.Method == http.MethodGet && r.URL.Path == "/export":
+		e, err := h.d.Export()
+		reply(w, e, err)
+	case r.Method == http.MethodPost && r.URL.Path == "/import":
+		var e driver.Export
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			reply(w, nil, fmt.Errorf("unable to decode export: %v", err))
+			return
+		}
+		results, err := h.d.Import(&e, r.URL.Query().Get("conflict"), r.URL.Query().Get("dry-run") == "true")
+		reply(w, results, err)
+	case r.Method == http.MethodPost && r.URL.Path == "/config/reload":
+		rc, ok := h.d.(ConfigReloader)
+		if !ok {
+			reply(w, nil, fmt.Errorf("config reload is not supported"))
+			return
+		}
+		restart, err := rc.ReloadConfig()
+		reply(w, ReloadResponse{RestartNeeded: restart}, err)
+	case r.Method == http.MethodPost && r.URL.Path == "/gc":
+		res, err := h.d.GC(r.URL.Query().Get("dry-run") == "true")
+		reply(w, res, err)
+	case r.Method == http.MethodGet && r.URL.Path == "/debug/goroutines":
+		w.Header().Set("Content-Type", "text/plain")
+		pprof.Lookup("goroutine").WriteTo(w, 2)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(errorResponse{Err: fmt.Sprintf("unknown endpoint %s %s", r.Method, r.URL.Path)})
+	}
+}
+
+func reply(w http.ResponseWriter, data interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(errorResponse{Err: err.Error()})
+		return
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	json.NewEncoder(w).Encode(data)
+}
+
+//Listen create the admin unix socket only accessible by root and, if gid is not -1, by this group
+func Listen(path string, gid int) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	mode := os.FileMode(0600)
+	if gid != -1 {
+		mode = 0660
+	}
+	//Never expose the socket, even briefly, before permissions are set: it is created in a private folder then moved
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".admin")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "admin.sock")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	if ul, ok := l.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false) //Bound path is moved, removed by the daemon at exit
+	}
+	if gid != -1 {
+		if err := os.Chown(tmp, -1, gid); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+//Serve answer admin calls on l (see Listen) until it is closed
+func Serve(l net.Listener, d Driver) error {
+	log.Infof("Listening for admin calls on %s", l.Addr())
+	return http.Serve(l, NewHandler(d))
+}
@@ -0,0 +1,129 @@
+package gluster
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sapk/docker-volume-gluster/gluster/config"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/cobra"
+)
+
+const (
+	//ConfigFlag flag to set the daemon config file
+	ConfigFlag = "config"
+)
+
+var (
+	//ConfigFile daemon config file (yaml, toml or json)
+	ConfigFile          = driver.CfgFolder + "config.yml"
+	defaultMountTimeout = driver.MountTimeout
+	configCmd           = &cobra.Command{
+		Use:   "config",
+		Short: "Manage daemon config file",
+	}
+	configValidateCmd = &cobra.Command{
+		Use:              "validate [FILE]",
+		Short:            "Check the config file (default to --config)",
+		Args:             cobra.MaximumNArgs(1),
+		PersistentPreRun: setupLogger,
+		SilenceUsage:     true,
+		RunE:             configValidate,
+	}
+)
+
+func setupConfigCmd() {
+	rootCmd.PersistentFlags().StringVar(&ConfigFile, ConfigFlag, envOrDefault("CONFIG_FILE", ConfigFile), "Daemon config file (yaml, toml or json), flags and env vars override it")
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func configValidate(cmd *cobra.Command, args []string) error {
+	path := ConfigFile
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if _, err := config.Load(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", path)
+	return nil
+}
+
+//loadConfig read the config file and apply it to settings not set by flag or env var
+func loadConfig(cmd *cobra.Command, args []string) {
+	setupLogger(cmd, args)
+	c, err := readConfig(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	applyConfig(cmd, c)
+}
+
+//readConfig read the config file, a missing file is an error only if it was explicitly set
+func readConfig(cmd *cobra.Command) (*config.Config, error) {
+	return config.LoadIfExist(ConfigFile, cmd.Flags().Changed(ConfigFlag) || os.Getenv("CONFIG_FILE") != "")
+}
+
+//notOverridden return true if the setting is not set by flag or env var
+func notOverridden(cmd *cobra.Command, flag, env string) bool {
+	if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+		return false
+	}
+	return env == "" || os.Getenv(env) == ""
+}
+
+func applyConfig(cmd *cobra.Command, c *config.Config) {
+	if c.BaseDir != "" && notOverridden(cmd, BasedirFlag, "") {
+		BaseDir = c.BaseDir
+	}
+	if c.StateDir != "" {
+		driver.CfgFolder = c.StateDir
+		if notOverridden(cmd, ClustersFlag, "CLUSTERS_FILE") {
+			driver.ClustersFile = filepath.Join(driver.CfgFolder, "clusters.yml")
+		}
+		if notOverridden(cmd, KeystoreFlag, "KEYSTORE") {
+			driver.KeystoreDir = filepath.Join(driver.CfgFolder, "keystore")
+		}
+	}
+	if c.MountUniq != nil && notOverridden(cmd, MountUniqNameFlag, "MOUNT_UNIQ") {
+		mountUniqName = *c.MountUniq
+	}
+	applyRuntimeConfig(cmd, c)
+}
+
+//applyRuntimeConfig apply settings that can be changed while the daemon is running, unset ones go back to default
+func applyRuntimeConfig(cmd *cobra.Command, c *config.Config) {
+	if notOverridden(cmd, VerboseFlag, "DEBUG") {
+		level := log.InfoLevel
+		if c.LogLevel != "" {
+			level, _ = log.ParseLevel(c.LogLevel) //Already validated
+		}
+		log.SetLevel(level)
+	}
+	driver.MountTimeout = defaultMountTimeout
+	if c.MountTimeout > 0 {
+		driver.MountTimeout = int(c.MountTimeout.Seconds())
+	}
+	driver.DefaultOptions = c.DefaultOptions
+	if driver.DefaultOptions == nil {
+		driver.DefaultOptions = map[string]string{}
+	}
+	driver.Clusters = c.Clusters
+	if driver.Clusters == nil {
+		driver.Clusters = map[string]driver.Cluster{}
+	}
+	driver.Profiles = c.Profiles
+	if driver.Profiles == nil {
+		driver.Profiles = map[string]map[string]string{}
+	}
+}
+
+func envDurationOrDefault(key string, def time.Duration) time.Duration {
+	if val, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return val
+	}
+	return def
+}
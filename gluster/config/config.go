@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/viper"
+)
+
+//Config daemon settings read from a yaml, toml or json file
+type Config struct {
+	BaseDir        string                       `mapstructure:"basedir"`
+	StateDir       string                       `mapstructure:"statedir"`
+	MountUniq      *bool                        `mapstructure:"mount-uniq"`
+	LogLevel       string                       `mapstructure:"log-level"`
+	MountTimeout   time.Duration                `mapstructure:"mount-timeout"`
+	DefaultOptions map[string]string            `mapstructure:"default-options"`
+	Clusters       map[string]driver.Cluster    `mapstructure:"clusters"`
+	Profiles       map[string]map[string]string `mapstructure:"profiles"`
+	Metrics        Metrics                      `mapstructure:"metrics"`
+	Health         Health                       `mapstructure:"health"`
+}
+
+//Metrics settings of the metrics endpoint
+type Metrics struct {
+	Listen string `mapstructure:"listen"`
+}
+
+//Health settings of the periodic check of mountpoints
+type Health struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	Reconcile bool          `mapstructure:"reconcile"`
+}
+
+//Load read and validate the config file, unknown settings are refused to catch typos
+func Load(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("unable to read config file %s: %v", path, err)
+	}
+	c := &Config{}
+	if err := v.UnmarshalExact(c); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return c, nil
+}
+
+//LoadIfExist read the config file if it exist, a missing file is only an error if required
+func LoadIfExist(path string, required bool) (*Config, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) && !required {
+		return &Config{}, nil
+	}
+	return Load(path)
+}
+
+//Validate check the settings
+func (c *Config) Validate() error {
+	var errs []string
+	if c.BaseDir != "" && !filepath.IsAbs(c.BaseDir) {
+		errs = append(errs, fmt.Sprintf("basedir need to be an absolute path: %s", c.BaseDir))
+	}
+	if c.StateDir != "" && !filepath.IsAbs(c.StateDir) {
+		errs = append(errs, fmt.Sprintf("statedir need to be an absolute path: %s", c.StateDir))
+	}
+	if c.LogLevel != "" {
+		if _, err := log.ParseLevel(c.LogLevel); err != nil {
+			errs = append(errs, fmt.Sprintf("log-level: %v", err))
+		}
+	}
+	if c.MountTimeout < 0 || (c.MountTimeout > 0 && c.MountTimeout < time.Second) {
+		errs = append(errs, fmt.Sprintf("mount-timeout need to be at least 1s: %s", c.MountTimeout))
+	}
+	if err := driver.ValidateOptions(c.DefaultOptions); err != nil {
+		errs = append(errs, fmt.Sprintf("default-options: %v", err))
+	}
+	for name, p := range c.Profiles {
+		if err := driver.ValidateProfile(p); err != nil {
+			errs = append(errs, fmt.Sprintf("profile %s: %v", name, err))
+		}
+	}
+	if p := c.DefaultOptions["profile"]; p != "" && c.Profiles[p] == nil && !driver.IsBuiltinProfile(p) {
+		errs = append(errs, fmt.Sprintf("default-options: unknown profile %s", p))
+	}
+	for name, cl := range c.Clusters {
+		if len(cl.Servers) == 0 {
+			errs = append(errs, fmt.Sprintf("cluster %s doesn't define any server", name))
+		} else if _, err := driver.ParseVolURI(strings.Join(cl.Servers, ",") + ":volume"); err != nil {
+			errs = append(errs, fmt.Sprintf("cluster %s: %v", name, err))
+		}
+	}
+	if c.Metrics.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Listen); err != nil {
+			errs = append(errs, fmt.Sprintf("metrics.listen: %v", err))
+		}
+	}
+	if c.Health.Interval < 0 {
+		errs = append(errs, fmt.Sprintf("health.interval can't be negative: %s", c.Health.Interval))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
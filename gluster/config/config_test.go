@@ -0,0 +1,91 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yml := filepath.Join(dir, "config.yml")
+	ioutil.WriteFile(yml, []byte(`
+basedir: /var/lib/gluster
+statedir: /etc/gluster/
+mount-uniq: true
+log-level: debug
+mount-timeout: 1m
+default-options:
+  log-level: WARNING
+  uid: "1000"
+clusters:
+  prod:
+    servers: [node-1, node-2]
+    ssl: true
+profiles:
+  media:
+    read-ahead-page-count: 16
+    direct-io-mode: disable
+metrics:
+  listen: ":9100"
+health:
+  interval: 30s
+  reconcile: true
+`), 0600)
+	c, err := Load(yml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BaseDir != "/var/lib/gluster" || c.MountUniq == nil || !*c.MountUniq || c.MountTimeout != time.Minute {
+		t.Errorf("Unexpected config %+v", c)
+	}
+	if c.DefaultOptions["uid"] != "1000" || len(c.Clusters["prod"].Servers) != 2 || !c.Clusters["prod"].IOEnabled() {
+		t.Errorf("Unexpected options or clusters %+v", c)
+	}
+	if c.Profiles["media"]["read-ahead-page-count"] != "16" {
+		t.Errorf("Unexpected profiles %+v", c.Profiles)
+	}
+	if c.Metrics.Listen != ":9100" || c.Health.Interval != 30*time.Second || !c.Health.Reconcile {
+		t.Errorf("Unexpected metrics or health %+v", c)
+	}
+
+	toml := filepath.Join(dir, "config.toml")
+	ioutil.WriteFile(toml, []byte("basedir = \"/var/lib/gluster\"\n[health]\ninterval = \"10s\"\n"), 0600)
+	if c, err := Load(toml); err != nil || c.Health.Interval != 10*time.Second {
+		t.Errorf("Unexpected toml config %+v (%v)", c, err)
+	}
+
+	tt := map[string]string{
+		"basedir: relative\n":                   "basedir",
+		"log-level: verbose\n":                  "log-level",
+		"mount-timeout: 10ms\n":                 "mount-timeout",
+		"default-options:\n  uid: root\n":       "default-options",
+		"clusters:\n  prod:\n    servers: []\n": "cluster prod",
+		"profiles:\n  db:\n    uid: 0\n":        "profile db",
+		"default-options:\n  profile: db\n":     "unknown profile",
+		"metrics:\n  listen: 9100\n":            "metrics.listen",
+		"health:\n  interval: -1s\n":            "health.interval",
+		"basdir: /typo\n":                       "basdir",
+	}
+	for content, expected := range tt {
+		ioutil.WriteFile(yml, []byte(content), 0600)
+		if _, err := Load(yml); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error about %s for %q, got %v", expected, content, err)
+		}
+	}
+
+	if _, err := LoadIfExist(filepath.Join(dir, "missing.yml"), false); err != nil {
+		t.Errorf("Expected missing optional config to be ignored, got %v", err)
+	}
+	if _, err := LoadIfExist(filepath.Join(dir, "missing.yml"), true); err == nil {
+		t.Error("Expected missing required config to fail")
+	}
+}
@@ -0,0 +1,31 @@
+package gluster
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sapk/docker-volume-gluster/gluster/config"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfigStateDir(t *testing.T) {
+	defer func(cfg, clusters, keystore string) {
+		driver.CfgFolder, driver.ClustersFile, driver.KeystoreDir = cfg, clusters, keystore
+	}(driver.CfgFolder, driver.ClustersFile, driver.KeystoreDir)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVar(&driver.KeystoreDir, KeystoreFlag, driver.KeystoreDir, "")
+	applyConfig(cmd, &config.Config{StateDir: "/var/lib/gluster"})
+	if driver.ClustersFile != filepath.Join("/var/lib/gluster", "clusters.yml") || driver.KeystoreDir != filepath.Join("/var/lib/gluster", "keystore") {
+		t.Errorf("Expected clusters file and keystore to follow statedir, got %s and %s", driver.ClustersFile, driver.KeystoreDir)
+	}
+
+	if err := cmd.Flags().Set(KeystoreFlag, "/etc/keystore"); err != nil {
+		t.Fatal(err)
+	}
+	applyConfig(cmd, &config.Config{StateDir: "/srv/gluster"})
+	if driver.KeystoreDir != "/etc/keystore" {
+		t.Errorf("Expected --keystore to override statedir, got %s", driver.KeystoreDir)
+	}
+}
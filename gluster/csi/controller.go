@@ -0,0 +1,122 @@
+package csi
+
+import (
+	"context"
+	"path"
+
+	spec "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//CreateVolume provision a subdir named after the volume in the gluster volume given by the voluri parameter
+func (d *Driver) CreateVolume(ctx context.Context, req *spec.CreateVolumeRequest) (*spec.CreateVolumeResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if err := validateCapabilities(req.GetVolumeCapabilities()); err != nil {
+		return nil, err
+	}
+	params := req.GetParameters()
+	if params["voluri"] == "" {
+		return nil, status.Error(codes.InvalidArgument, "voluri parameter is required")
+	}
+	u, err := driver.ParseVolURI(params["voluri"])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "voluri parameter is malformated: %v", err)
+	}
+	u.Subdir = path.Join("/", u.Subdir, req.GetName())
+	opts := make(map[string]string, len(params))
+	for k, val := range params {
+		if k != "voluri" {
+			opts[k] = val
+		}
+	}
+	v := &driver.GlusterVolume{VolumeURI: u.String(), Options: opts}
+	if err := validateVolume(v); err != nil {
+		return nil, err
+	}
+	if err := d.mounter.Provision(v); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to provision %s: %v", v.VolumeURI, err)
+	}
+	return &spec.CreateVolumeResponse{
+		Volume: &spec.Volume{
+			VolumeId:      v.VolumeURI,
+			VolumeContext: opts,
+		},
+	}, nil
+}
+
+//DeleteVolume forget the volume, data stay on the gluster volume
+func (d *Driver) DeleteVolume(ctx context.Context, req *spec.DeleteVolumeRequest) (*spec.DeleteVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	return &spec.DeleteVolumeResponse{}, nil
+}
+
+//ValidateVolumeCapabilities check that the volume support the requested capabilities
+func (d *Driver) ValidateVolumeCapabilities(ctx context.Context, req *spec.ValidateVolumeCapabilitiesRequest) (*spec.ValidateVolumeCapabilitiesResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if _, err := driver.ParseVolURI(req.GetVolumeId()); err != nil {
+		return nil, status.Errorf(codes.NotFound, "volume %s is not a valid voluri: %v", req.GetVolumeId(), err)
+	}
+	if err := validateCapabilities(req.GetVolumeCapabilities()); err != nil {
+		if status.Code(err) == codes.InvalidArgument && len(req.GetVolumeCapabilities()) > 0 {
+			return &spec.ValidateVolumeCapabilitiesResponse{Message: err.Error()}, nil
+		}
+		return nil, err
+	}
+	return &spec.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &spec.ValidateVolumeCapabilitiesResponse_Confirmed{
+			VolumeContext:      req.GetVolumeContext(),
+			VolumeCapabilities: req.GetVolumeCapabilities(),
+			Parameters:         req.GetParameters(),
+		},
+	}, nil
+}
+
+//ControllerGetCapabilities return capabilities of the controller
+func (d *Driver) ControllerGetCapabilities(ctx context.Context, req *spec.ControllerGetCapabilitiesRequest) (*spec.ControllerGetCapabilitiesResponse, error) {
+	return &spec.ControllerGetCapabilitiesResponse{
+		Capabilities: []*spec.ControllerServiceCapability{
+			{
+				Type: &spec.ControllerServiceCapability_Rpc{
+					Rpc: &spec.ControllerServiceCapability_RPC{
+						Type: spec.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+//validateCapabilities check that only filesystem access is requested
+func validateCapabilities(caps []*spec.VolumeCapability) error {
+	if len(caps) == 0 {
+		return status.Error(codes.InvalidArgument, "volume capabilities are required")
+	}
+	for _, c := range caps {
+		if c.GetBlock() != nil {
+			return status.Error(codes.InvalidArgument, "block access is not supported")
+		}
+		if c.GetMount() == nil {
+			return status.Error(codes.InvalidArgument, "mount access type is required")
+		}
+		if c.GetAccessMode() == nil {
+			return status.Error(codes.InvalidArgument, "access mode is required")
+		}
+	}
+	return nil
+}
+
+//validateVolume check voluri and options as the docker driver does on Create
+func validateVolume(v *driver.GlusterVolume) error {
+	if err := v.Validate(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid volume: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package csi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	spec "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"google.golang.org/grpc"
+)
+
+//Mounter needed interface to provision and mount gluster volumes
+type Mounter interface {
+	Provision(v *driver.GlusterVolume) error
+	MountVolume(v *driver.GlusterVolume, path string) error
+	UnmountPath(path string) error
+	IsMounted(path string) (bool, error)
+	ReleaseCredentials(path string)
+}
+
+//Driver CSI driver responding to Identity, Controller and Node calls
+type Driver struct {
+	spec.UnimplementedIdentityServer
+	spec.UnimplementedControllerServer
+	spec.UnimplementedNodeServer
+
+	name    string
+	version string
+	nodeID  string
+	mounter Mounter
+}
+
+//New return a CSI driver using mounter to act on gluster volumes
+func New(name, version, nodeID string, mounter Mounter) *Driver {
+	return &Driver{
+		name:    name,
+		version: version,
+		nodeID:  nodeID,
+		mounter: mounter,
+	}
+}
+
+//Serve listen for CSI calls on endpoint (unix:///path/csi.sock or tcp://host:port)
+func (d *Driver) Serve(endpoint string) error {
+	l, err := listen(endpoint)
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer(grpc.UnaryInterceptor(logCall))
+	spec.RegisterIdentityServer(s, d)
+	spec.RegisterControllerServer(s, d)
+	spec.RegisterNodeServer(s, d)
+	log.Infof("Listening for CSI calls on %s", endpoint)
+	return s.Serve(l)
+}
+
+func listen(endpoint string) (net.Listener, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %s: %v", endpoint, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "unix":
+		path := u.Path
+		if u.Host != "" { //unix://relative/path
+			path = u.Host + path
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return net.Listen("unix", path)
+	case "tcp":
+		return net.Listen("tcp", u.Host)
+	}
+	return nil, fmt.Errorf("unsupported endpoint scheme %s", u.Scheme)
+}
+
+func logCall(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	log.Debugf("Entering %s: %v", info.FullMethod, req)
+	resp, err := handler(ctx, req)
+	if err != nil {
+		log.Debugf("Error %s: %v", info.FullMethod, err)
+	}
+	return resp, err
+}
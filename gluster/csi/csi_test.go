@@ -0,0 +1,152 @@
+package csi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	spec "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeMounter struct {
+	provisioned []string
+	mounts      map[string]*driver.GlusterVolume
+	released    []string
+}
+
+func (m *fakeMounter) Provision(v *driver.GlusterVolume) error {
+	m.provisioned = append(m.provisioned, v.VolumeURI)
+	return nil
+}
+
+func (m *fakeMounter) MountVolume(v *driver.GlusterVolume, path string) error {
+	m.mounts[path] = v
+	return nil
+}
+
+func (m *fakeMounter) UnmountPath(path string) error {
+	delete(m.mounts, path)
+	return nil
+}
+
+func (m *fakeMounter) ReleaseCredentials(path string) {
+	m.released = append(m.released, path)
+}
+
+func (m *fakeMounter) IsMounted(path string) (bool, error) {
+	_, ok := m.mounts[path]
+	return ok, nil
+}
+
+var mountCap = &spec.VolumeCapability{
+	AccessType: &spec.VolumeCapability_Mount{Mount: &spec.VolumeCapability_MountVolume{}},
+	AccessMode: &spec.VolumeCapability_AccessMode{Mode: spec.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+}
+
+func TestCreateVolume(t *testing.T) {
+	m := &fakeMounter{mounts: map[string]*driver.GlusterVolume{}}
+	d := New("gluster.test", "test", "node-1", m)
+	ctx := context.Background()
+
+	resp, err := d.CreateVolume(ctx, &spec.CreateVolumeRequest{
+		Name:               "pvc-1",
+		VolumeCapabilities: []*spec.VolumeCapability{mountCap},
+		Parameters:         map[string]string{"voluri": "node-1,node-2:vol/k8s", "uid": "1000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := resp.GetVolume().GetVolumeId(); id != "node-1,node-2:vol/k8s/pvc-1" {
+		t.Errorf("Expected volume id node-1,node-2:vol/k8s/pvc-1, got %s", id)
+	}
+	if resp.GetVolume().GetVolumeContext()["uid"] != "1000" {
+		t.Errorf("Expected options in volume context, got %v", resp.GetVolume().GetVolumeContext())
+	}
+	if len(m.provisioned) != 1 {
+		t.Errorf("Expected volume to be provisioned, got %v", m.provisioned)
+	}
+
+	tt := []*spec.CreateVolumeRequest{
+		{VolumeCapabilities: []*spec.VolumeCapability{mountCap}, Parameters: map[string]string{"voluri": "node-1:vol"}},
+		{Name: "pvc-2", Parameters: map[string]string{"voluri": "node-1:vol"}},
+		{Name: "pvc-2", VolumeCapabilities: []*spec.VolumeCapability{mountCap}},
+		{Name: "pvc-2", VolumeCapabilities: []*spec.VolumeCapability{mountCap}, Parameters: map[string]string{"voluri": "node-1"}},
+		{Name: "pvc-2", VolumeCapabilities: []*spec.VolumeCapability{mountCap}, Parameters: map[string]string{"voluri": "node-1:vol", "uid": "root"}},
+		{Name: "pvc-2", VolumeCapabilities: []*spec.VolumeCapability{mountCap}, Parameters: map[string]string{"voluri": "node-1:vol", "backend": "nfs4"}},
+		{Name: "pvc-2", VolumeCapabilities: []*spec.VolumeCapability{mountCap}, Parameters: map[string]string{"voluri": "node-1:vol", "profile": "unknown"}},
+		{Name: "pvc-2", VolumeCapabilities: []*spec.VolumeCapability{{
+			AccessType: &spec.VolumeCapability_Block{Block: &spec.VolumeCapability_BlockVolume{}},
+			AccessMode: mountCap.AccessMode,
+		}}, Parameters: map[string]string{"voluri": "node-1:vol"}},
+	}
+	for _, req := range tt {
+		if _, err := d.CreateVolume(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for %v, got %v", req, err)
+		}
+	}
+}
+
+func TestNodePublishVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-csi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "target")
+
+	m := &fakeMounter{mounts: map[string]*driver.GlusterVolume{}}
+	d := New("gluster.test", "test", "node-1", m)
+	ctx := context.Background()
+
+	req := &spec.NodePublishVolumeRequest{
+		VolumeId:         "node-1:vol/pvc-1",
+		TargetPath:       target,
+		VolumeCapability: mountCap,
+		Readonly:         true,
+	}
+	for i := 0; i < 2; i++ { //Second call need to be idempotent
+		if _, err := d.NodePublishVolume(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, ok := m.mounts[target]
+	if !ok || !v.ReadOnly() {
+		t.Fatalf("Expected %s to be mounted read-only, got %v", target, m.mounts)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := d.NodeUnpublishVolume(ctx, &spec.NodeUnpublishVolumeRequest{VolumeId: req.VolumeId, TargetPath: target}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(m.mounts) != 0 {
+		t.Errorf("Expected no mount left, got %v", m.mounts)
+	}
+	if len(m.released) == 0 || m.released[0] != target {
+		t.Errorf("Expected credentials of %s to be released, got %v", target, m.released)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", target, err)
+	}
+
+	if _, err := d.NodePublishVolume(ctx, &spec.NodePublishVolumeRequest{VolumeId: req.VolumeId, VolumeCapability: mountCap}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without target path, got %v", err)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	d := New("gluster.test", "test", "node-1", &fakeMounter{})
+	info, err := d.GetPluginInfo(context.Background(), &spec.GetPluginInfoRequest{})
+	if err != nil || info.GetName() != "gluster.test" || info.GetVendorVersion() != "test" {
+		t.Errorf("Unexpected plugin info %v (%v)", info, err)
+	}
+	node, err := d.NodeGetInfo(context.Background(), &spec.NodeGetInfoRequest{})
+	if err != nil || node.GetNodeId() != "node-1" {
+		t.Errorf("Unexpected node info %v (%v)", node, err)
+	}
+}
@@ -0,0 +1,36 @@
+package csi
+
+import (
+	"context"
+
+	spec "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+//GetPluginInfo return name and version of the driver
+func (d *Driver) GetPluginInfo(ctx context.Context, req *spec.GetPluginInfoRequest) (*spec.GetPluginInfoResponse, error) {
+	return &spec.GetPluginInfoResponse{
+		Name:          d.name,
+		VendorVersion: d.version,
+	}, nil
+}
+
+//GetPluginCapabilities return capabilities of the driver
+func (d *Driver) GetPluginCapabilities(ctx context.Context, req *spec.GetPluginCapabilitiesRequest) (*spec.GetPluginCapabilitiesResponse, error) {
+	return &spec.GetPluginCapabilitiesResponse{
+		Capabilities: []*spec.PluginCapability{
+			{
+				Type: &spec.PluginCapability_Service_{
+					Service: &spec.PluginCapability_Service{
+						Type: spec.PluginCapability_Service_CONTROLLER_SERVICE,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+//Probe return the readiness of the driver
+func (d *Driver) Probe(ctx context.Context, req *spec.ProbeRequest) (*spec.ProbeResponse, error) {
+	return &spec.ProbeResponse{Ready: wrapperspb.Bool(true)}, nil
+}
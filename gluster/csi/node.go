@@ -0,0 +1,88 @@
+package csi
+
+import (
+	"context"
+	"os"
+
+	spec "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//NodePublishVolume mount the volume on the target path
+func (d *Driver) NodePublishVolume(ctx context.Context, req *spec.NodePublishVolumeRequest) (*spec.NodePublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if req.GetTargetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path is required")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability is required")
+	}
+	if err := validateCapabilities([]*spec.VolumeCapability{req.GetVolumeCapability()}); err != nil {
+		return nil, err
+	}
+	opts := make(map[string]string, len(req.GetVolumeContext())+1)
+	for k, val := range req.GetVolumeContext() {
+		opts[k] = val
+	}
+	mode := req.GetVolumeCapability().GetAccessMode().GetMode()
+	if req.GetReadonly() || mode == spec.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY || mode == spec.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY {
+		opts["ro"] = "true"
+	}
+	v := &driver.GlusterVolume{VolumeURI: req.GetVolumeId(), Options: opts}
+	if err := validateVolume(v); err != nil {
+		return nil, status.Errorf(codes.NotFound, "volume %s: %v", req.GetVolumeId(), err)
+	}
+
+	mounted, err := d.mounter.IsMounted(req.GetTargetPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if mounted {
+		return &spec.NodePublishVolumeResponse{}, nil
+	}
+	if err := os.MkdirAll(req.GetTargetPath(), 0750); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err := d.mounter.MountVolume(v, req.GetTargetPath()); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to mount %s: %v", req.GetVolumeId(), err)
+	}
+	return &spec.NodePublishVolumeResponse{}, nil
+}
+
+//NodeUnpublishVolume unmount the volume from the target path
+func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *spec.NodeUnpublishVolumeRequest) (*spec.NodeUnpublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if req.GetTargetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path is required")
+	}
+	mounted, err := d.mounter.IsMounted(req.GetTargetPath())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if mounted {
+		if err := d.mounter.UnmountPath(req.GetTargetPath()); err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to unmount %s: %v", req.GetTargetPath(), err)
+		}
+	}
+	d.mounter.ReleaseCredentials(req.GetTargetPath())
+	if err := os.Remove(req.GetTargetPath()); err != nil && !os.IsNotExist(err) {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &spec.NodeUnpublishVolumeResponse{}, nil
+}
+
+//NodeGetCapabilities return capabilities of the node, no staging is needed
+func (d *Driver) NodeGetCapabilities(ctx context.Context, req *spec.NodeGetCapabilitiesRequest) (*spec.NodeGetCapabilitiesResponse, error) {
+	return &spec.NodeGetCapabilitiesResponse{}, nil
+}
+
+//NodeGetInfo return the id of the node
+func (d *Driver) NodeGetInfo(ctx context.Context, req *spec.NodeGetInfoRequest) (*spec.NodeGetInfoResponse, error) {
+	return &spec.NodeGetInfoResponse{NodeId: d.nodeID}, nil
+}
@@ -0,0 +1,295 @@
+package driver
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sapk/docker-volume-gluster/common"
+)
+
+//VolumeInfo state of a volume as shown to operators
+type VolumeInfo struct {
+	Name             string            `json:"name"`
+	VolumeURI        string            `json:"voluri"`
+	Options          map[string]string `json:"options,omitempty"`
+	Mountpoint       string            `json:"mountpoint"`
+	Connections      int               `json:"connections"`
+	MountConnections int               `json:"mount_connections"`
+	Mounted          bool              `json:"mounted"`
+}
+
+//Unused return true if no container use the volume and it is not mounted
+func (i VolumeInfo) Unused() bool {
+	return i.Connections == 0 && !i.Mounted
+}
+
+//Volumes return the state of all volumes sorted by name with their live mount status
+func (d *GlusterDriver) Volumes() ([]VolumeInfo, error) {
+	mounts, err := listMounts()
+	if err != nil {
+		return nil, err
+	}
+	d.GetLock().RLock()
+	defer d.GetLock().RUnlock()
+	infos := make([]VolumeInfo, 0, len(d.volumes))
+	for name := range d.volumes {
+		infos = append(infos, d.volumeInfo(name, mounts))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+//Volume return the state of a volume with its live mount status
+func (d *GlusterDriver) Volume(name string) (*VolumeInfo, error) {
+	mounts, err := listMounts()
+	if err != nil {
+		return nil, err
+	}
+	d.GetLock().RLock()
+	defer d.GetLock().RUnlock()
+	if _, ok := d.volumes[name]; !ok {
+		return nil, fmt.Errorf("volume %s not found", name)
+	}
+	info := d.volumeInfo(name, mounts)
+	return &info, nil
+}
+
+//volumeInfo build the state of a volume (lock need to be hold)
+func (d *GlusterDriver) volumeInfo(name string, mounts map[string]bool) VolumeInfo {
+	v := d.volumes[name]
+	info := VolumeInfo{
+		Name:        name,
+		VolumeURI:   v.VolumeURI,
+		Options:     redactOptions(v.Options),
+		Connections: v.Connections,
+	}
+	if m, ok := d.mounts[v.Mount]; ok {
+		info.Mountpoint = m.Path
+		info.MountConnections = m.Connections
+		info.Mounted = mounts[m.Path]
+	}
+	return info
+}
+
+//RemoveVolume remove a volume, with force the connections of a volume that is not mounted anymore are reset before
+func (d *GlusterDriver) RemoveVolume(name string, force bool) error {
+	info, err := d.Volume(name)
+	if err != nil {
+		return err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	v, ok := d.volumes[name]
+	if !ok {
+		return fmt.Errorf("volume %s not found", name)
+	}
+	if force && v.Connections > 0 && !info.Mounted {
+		log.Warnf("Resetting connections of %s that is not mounted", name)
+		common.SetN(0, v)
+		v.Containers = nil
+	}
+	if v.Connections > 0 {
+		return fmt.Errorf("volume %s is currently used by a container", name)
+	}
+	if m, ok := d.mounts[v.Mount]; ok && !d.mountShared(name, v.Mount) {
+		if err := os.Remove(m.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(d.mounts, v.Mount)
+	}
+	d.releaseCredentials(v)
+	delete(d.volumes, name)
+	if err := d.SaveConfig(); err != nil {
+		return err
+	}
+	if d.registry != nil {
+		return d.registry.Delete(name)
+	}
+	return nil
+}
+
+//mountShared return true if another volume use the mountpoint (lock need to be hold)
+func (d *GlusterDriver) mountShared(name, mount string) bool {
+	for n, v := range d.volumes {
+		if n != name && v.Mount == mount {
+			return true
+		}
+	}
+	return false
+}
+
+//Prune remove all volumes not used by a container nor mounted and return their names
+func (d *GlusterDriver) Prune() ([]string, error) {
+	infos, err := d.Volumes()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, i := range infos {
+		if !i.Unused() {
+			continue
+		}
+		if err := d.RemoveVolume(i.Name, false); err != nil {
+			return removed, err
+		}
+		removed = append(removed, i.Name)
+	}
+	return removed, nil
+}
+
+//ForceUnmount unmount the mountpoint of a volume even if used and reset connections of all volumes sharing it
+func (d *GlusterDriver) ForceUnmount(name string) error {
+	mounts, err := listMounts()
+	if err != nil {
+		return err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	v, ok := d.volumes[name]
+	if !ok {
+		return fmt.Errorf("volume %s not found", name)
+	}
+	m, ok := d.mounts[v.Mount]
+	if !ok {
+		return fmt.Errorf("mount %s not found", v.Mount)
+	}
+	if mounts[m.Path] {
+		if err := d.UnmountPath(m.Path); err != nil {
+			return err
+		}
+	}
+	common.SetN(0, m)
+	for _, o := range d.volumes {
+		if o.Mount == v.Mount {
+			common.SetN(0, o)
+		}
+	}
+	return d.SaveConfig()
+}
+
+//Remount unmount (if needed) and mount again the mountpoint of a volume keeping its connections
+func (d *GlusterDriver) Remount(name string) error {
+	mounts, err := listMounts()
+	if err != nil {
+		return err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	v, ok := d.volumes[name]
+	if !ok {
+		return fmt.Errorf("volume %s not found", name)
+	}
+	m, ok := d.mounts[v.Mount]
+	if !ok {
+		return fmt.Errorf("mount %s not found", v.Mount)
+	}
+	if mounts[m.Path] {
+		if err := d.UnmountPath(m.Path); err != nil {
+			return err
+		}
+	}
+	return d.mountVolume(v, m.Path)
+}
+
+//Reload read again the persistence file replacing the state in memory
+func (d *GlusterDriver) Reload() error {
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	return d.loadConfig()
+}
+
+//Reconcile align mountpoints with connections: used but not mounted are mounted and unused but mounted are unmounted
+func (d *GlusterDriver) Reconcile() ([]string, error) {
+	mounts, err := listMounts()
+	if err != nil {
+		return nil, err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	var actions []string
+	for name, m := range d.mounts {
+		mounted := mounts[m.Path]
+		switch {
+		case m.Connections > 0 && !mounted:
+			v := d.volumeOfMount(name)
+			if v == nil {
+				continue
+			}
+			if err := d.mountVolume(v, m.Path); err != nil {
+				return actions, fmt.Errorf("unable to mount %s: %v", m.Path, err)
+			}
+			actions = append(actions, "mounted "+m.Path)
+		case m.Connections == 0 && mounted:
+			if err := d.UnmountPath(m.Path); err != nil {
+				return actions, fmt.Errorf("unable to unmount %s: %v", m.Path, err)
+			}
+			actions = append(actions, "unmounted "+m.Path)
+		}
+	}
+	sort.Strings(actions)
+	return actions, nil
+}
+
+//RestoreMounts mount again at startup the mountpoints in use that didn't survive the previous daemon (gfapi mounts are served by the daemon process), connections of the ones that can't be mounted are reset so that they are mounted on next use instead of returning the bare directory
+func (d *GlusterDriver) RestoreMounts() ([]string, error) {
+	mounts, err := listMounts()
+	if err != nil {
+		return nil, err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	var restored []string
+	reset := false
+	for name, m := range d.mounts {
+		if m.Connections == 0 {
+			continue
+		}
+		if mounts[m.Path] {
+			if _, err := os.Stat(m.Path); !isNotConnected(err) {
+				continue
+			}
+			if err := d.UnmountPath(m.Path); err != nil { //Left by a daemon that didn't stop cleanly
+				log.Warnf("Unable to unmount disconnected %s: %v", m.Path, err)
+			}
+		}
+		if v := d.volumeOfMount(name); v == nil {
+			err = fmt.Errorf("no volume use it")
+		} else if err = d.mountVolume(v, m.Path); err == nil {
+			restored = append(restored, m.Path)
+			continue
+		}
+		log.Warnf("Unable to mount again %s, resetting its connections: %v", m.Path, err)
+		common.SetN(0, m)
+		for _, o := range d.volumes {
+			if o.Mount == name {
+				common.SetN(0, o)
+				o.Containers = nil
+			}
+		}
+		reset = true
+	}
+	sort.Strings(restored)
+	if reset {
+		return restored, d.SaveConfig()
+	}
+	return restored, nil
+}
+
+//isNotConnected return true if err come from a FUSE mountpoint whose process is gone
+func isNotConnected(err error) bool {
+	pe, ok := err.(*os.PathError)
+	return ok && pe.Err == syscall.ENOTCONN
+}
+
+//volumeOfMount return a volume using the mountpoint (lock need to be hold)
+func (d *GlusterDriver) volumeOfMount(mount string) *GlusterVolume {
+	for _, v := range d.volumes {
+		if v.Mount == mount {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestAdminVolumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, proc string) { CfgFolder, ProcMounts = cfg, proc }(CfgFolder, ProcMounts)
+	CfgFolder = filepath.Join(dir, "cfg")
+	ProcMounts = filepath.Join(dir, "mounts")
+
+	root := filepath.Join(dir, "root")
+	d := Init(root, false)
+	for _, name := range []string{"used", "stale", "unused"} {
+		if err := d.Create(&volume.CreateRequest{Name: name, Options: map[string]string{"voluri": "node:" + name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.volumes["used"].Connections, d.mounts["used"].Connections = 1, 1
+	d.volumes["stale"].Connections, d.mounts["stale"].Connections = 2, 2
+	if err := ioutil.WriteFile(ProcMounts, []byte("node:used "+filepath.Join(root, "used")+" fuse.glusterfs rw 0 0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := d.Volumes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, i := range infos {
+		names = append(names, i.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"stale", "unused", "used"}) {
+		t.Errorf("Expected volumes sorted by name, got %v", names)
+	}
+	if i, err := d.Volume("used"); err != nil || !i.Mounted || i.Mountpoint != filepath.Join(root, "used") {
+		t.Errorf("Expected used to be mounted, got %v (%v)", i, err)
+	}
+
+	removed, err := d.Prune()
+	if err != nil || !reflect.DeepEqual(removed, []string{"unused"}) {
+		t.Errorf("Expected only unused to be pruned, got %v (%v)", removed, err)
+	}
+	if err := d.RemoveVolume("stale", false); err == nil {
+		t.Error("Expected stale volume to not be removed without force")
+	}
+	if err := d.RemoveVolume("stale", true); err != nil {
+		t.Errorf("Expected stale volume to be removed with force, got %v", err)
+	}
+	if err := d.RemoveVolume("used", true); err == nil {
+		t.Error("Expected mounted volume to not be removed even with force")
+	}
+
+	d = Init(root, false) //Reload from persistence
+	if _, ok := d.volumes["used"]; !ok || len(d.volumes) != 1 {
+		t.Errorf("Expected only used volume to be persisted, got %v", d.volumes)
+	}
+}
+
+func TestAdminForceUnmountReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, proc string) { CfgFolder, ProcMounts = cfg, proc }(CfgFolder, ProcMounts)
+	CfgFolder = filepath.Join(dir, "cfg")
+	ProcMounts = filepath.Join(dir, "mounts")
+	if err := ioutil.WriteFile(ProcMounts, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := Init(filepath.Join(dir, "root"), false)
+	if err := d.Create(&volume.CreateRequest{Name: "test", Options: map[string]string{"voluri": "node:test"}}); err != nil {
+		t.Fatal(err)
+	}
+	d.volumes["test"].Connections, d.mounts["test"].Connections = 2, 2
+	if err := d.ForceUnmount("test"); err != nil {
+		t.Fatal(err)
+	}
+	if d.volumes["test"].Connections != 0 || d.mounts["test"].Connections != 0 {
+		t.Errorf("Expected connections to be reset, got %d/%d", d.volumes["test"].Connections, d.mounts["test"].Connections)
+	}
+
+	delete(d.volumes, "test")
+	if err := d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.volumes["test"]; !ok {
+		t.Error("Expected volume to be restored from persistence file")
+	}
+	if actions, err := d.Reconcile(); err != nil || len(actions) != 0 {
+		t.Errorf("Expected nothing to reconcile, got %v (%v)", actions, err)
+	}
+}
@@ -0,0 +1,66 @@
+package driver
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+//ensureVolume create the volume if it is unknown and defined in the shared registry or auto creation is enabled for its name
+func (d *GlusterDriver) ensureVolume(name string) error {
+	d.GetLock().RLock()
+	_, ok := d.volumes[name]
+	d.GetLock().RUnlock()
+	if ok {
+		return nil
+	}
+	if d.registry != nil {
+		v, err := d.registry.Get(name)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			log.Infof("Volume %s is unknown, creating it from shared registry", name)
+			opts := map[string]string{"voluri": v.VolumeURI}
+			for k, val := range v.Options {
+				opts[k] = val
+			}
+			return d.Create(&volume.CreateRequest{Name: name, Options: opts})
+		}
+	}
+	r, err := autoCreateRequest(name)
+	if err != nil || r == nil {
+		return err
+	}
+	log.Infof("Volume %s is unknown, creating it on %s", name, r.Options["voluri"])
+	return d.Create(r)
+}
+
+//autoCreateRequest return the create request of an unknown volume or nil if it must not be auto created
+func autoCreateRequest(name string) (*volume.CreateRequest, error) {
+	if AutoCreateVolURI == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(AutoCreatePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto create pattern: %v", err)
+	}
+	if !re.MatchString(name) {
+		return nil, nil
+	}
+	u, err := ParseVolURI(AutoCreateVolURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto create voluri: %v", err)
+	}
+	u.Subdir = path.Join("/", u.Subdir, name)
+	return &volume.CreateRequest{
+		Name: name,
+		Options: map[string]string{
+			"voluri": u.String(),
+			"mkdir":  "true",
+		},
+	}, nil
+}
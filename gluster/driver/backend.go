@@ -0,0 +1,142 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//DefaultBackend backend used by volumes that don't set the backend option
+var DefaultBackend = "fuse"
+
+//Backend way of mounting gluster volumes on the host
+type Backend interface {
+	//Mount mount the volume described by spec on spec.Path
+	Mount(d *GlusterDriver, spec MountSpec) error
+	//Unmount unmount path, handled is false if path was not mounted by this backend
+	Unmount(d *GlusterDriver, path string) (handled bool, err error)
+}
+
+//MountSpec everything needed by a backend to mount a volume
+type MountSpec struct {
+	URI      *VolURI //Servers already expanded
+	SSL      SSLConfig
+	Options  map[string]string //Volume options completed by defaults and profile
+	ReadOnly bool
+	Mkdir    bool //Create the subdir on the volume if it doesn't exist
+	Path     string
+}
+
+//backends available in this build, gfapi is only registered when built with the gfapi tag
+var backends = map[string]Backend{
+	"fuse": fuseBackend{},
+}
+
+//knownBackends all backends, available in this build or not
+var knownBackends = []string{"fuse", "gfapi"}
+
+//validateBackend check the backend option
+func validateBackend(opts map[string]string) error {
+	if err := validateProtocol(opts); err != nil {
+		return err
+	}
+	name := opts["backend"]
+	if name == "" {
+		return nil
+	}
+	for _, b := range knownBackends {
+		if b == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown backend %s (%s)", name, strings.Join(knownBackends, ", "))
+}
+
+//backendOf return the backend used by a volume, protocol=nfs always use the nfs backend
+func backendOf(opts map[string]string) (Backend, error) {
+	switch opts["protocol"] {
+	case "nfs":
+		return nfsBackend{}, nil
+	case "", "glusterfs":
+	default:
+		return nil, fmt.Errorf("unknown protocol %s (%s)", opts["protocol"], strings.Join(Protocols, ", "))
+	}
+	name := opts["backend"]
+	if name == "" {
+		name = DefaultBackend
+	}
+	b, ok := backends[name]
+	if !ok {
+		if err := validateBackend(map[string]string{"backend": name}); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("backend %s is not available in this build (build with -tags %s)", name, name)
+	}
+	return b, nil
+}
+
+//CheckBackend return an error if the backend is not available in this build
+func CheckBackend(name string) error {
+	_, err := backendOf(map[string]string{"backend": name})
+	return err
+}
+
+//BackendNames return the backends available in this build
+func BackendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//processBackend backend serving mounts from the daemon process, they stop with it
+type processBackend interface {
+	io.Closer
+	//Serves return true if path is served by the backend
+	Serves(path string) bool
+}
+
+//closeBackends stop the backends serving mounts from the daemon process, inUse give the connections of the mountpoints still used
+func closeBackends(inUse map[string]int) {
+	for name, b := range backends {
+		if c, ok := b.(processBackend); ok {
+			for path, n := range inUse {
+				if c.Serves(path) {
+					log.Warnf("Stopping %s still used by %d container(s), it will be mounted again at next start of the daemon", path, n)
+				}
+			}
+			if err := c.Close(); err != nil {
+				log.Warnf("Unable to stop backend %s: %v", name, err)
+			}
+		}
+	}
+}
+
+//fuseBackend mount volumes with the glusterfs FUSE client
+type fuseBackend struct{}
+
+func (fuseBackend) Mount(d *GlusterDriver, spec MountSpec) error {
+	args, err := spec.URI.Args()
+	if err != nil {
+		return err
+	}
+	if spec.Mkdir && spec.URI.Subdir != "" {
+		if err := d.mkdirSubdir(spec.URI, spec.SSL.cmdArgs()); err != nil {
+			return fmt.Errorf("unable to create %s on volume %s: %v", spec.URI.Subdir, spec.URI.Volume, err)
+		}
+	}
+	args += spec.SSL.cmdArgs() + clientArgs(spec.Options)
+	if spec.ReadOnly {
+		args += " --read-only"
+	}
+	return d.RunCmd(fmt.Sprintf("glusterfs %s %s", args, spec.Path))
+}
+
+func (fuseBackend) Unmount(d *GlusterDriver, path string) (bool, error) {
+	return true, d.RunCmd(fmt.Sprintf("/usr/bin/umount %s", path))
+}
@@ -0,0 +1,145 @@
+//go:build gfapi
+// +build gfapi
+
+package driver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sapk/docker-volume-gluster/gluster/gfapi"
+)
+
+//gfapiLogLevels glusterfs log levels (gf_loglevel_t)
+var gfapiLogLevels = map[string]int{"NONE": 0, "CRITICAL": 3, "ERROR": 4, "WARNING": 5, "INFO": 7, "DEBUG": 8, "TRACE": 9}
+
+func init() {
+	backends["gfapi"] = &gfapiBackend{mounts: make(map[string]*gfapi.Mount)}
+}
+
+//gfapiBackend serve volumes from the daemon process with libgfapi and FUSE, mounts stop with the daemon
+type gfapiBackend struct {
+	lock   sync.Mutex
+	mounts map[string]*gfapi.Mount
+}
+
+func (b *gfapiBackend) Mount(d *GlusterDriver, spec MountSpec) error {
+	c, err := gfapiConfig(spec)
+	if err != nil {
+		return err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.mounts[spec.Path]; ok {
+		return fmt.Errorf("%s is already served by gfapi backend", spec.Path)
+	}
+	log.Debugf("Serving %s/%s on %s with gfapi", c.Volume, c.Subdir, spec.Path)
+	m, err := gfapi.NewMount(spec.Path, *c)
+	if err != nil {
+		return fmt.Errorf("unable to serve %s with gfapi: %v", spec.URI.Volume, err)
+	}
+	b.mounts[spec.Path] = m
+	return nil
+}
+
+func (b *gfapiBackend) Unmount(d *GlusterDriver, path string) (bool, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	m, ok := b.mounts[path]
+	if !ok {
+		return false, nil
+	}
+	delete(b.mounts, path)
+	return true, m.Unmount()
+}
+
+//Serves return true if path is served by the backend
+func (b *gfapiBackend) Serves(path string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	_, ok := b.mounts[path]
+	return ok
+}
+
+//Close unmount all volumes served by the backend
+func (b *gfapiBackend) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var errs []string
+	for path, m := range b.mounts {
+		log.Infof("Unmounting %s served by gfapi", path)
+		if err := m.Unmount(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+		}
+		delete(b.mounts, path)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+//gfapiConfig translate the mount of a volume to the settings of a gfapi mount
+func gfapiConfig(spec MountSpec) (*gfapi.Config, error) {
+	u := spec.URI
+	transport, err := u.transport()
+	if err != nil {
+		return nil, err
+	}
+	c := &gfapi.Config{
+		Volume:        u.Volume,
+		Subdir:        u.Subdir,
+		LogLevel:      gfapiLogLevels["INFO"],
+		XlatorOptions: make(map[string]map[string]string),
+		ReadOnly:      spec.ReadOnly,
+		Mkdir:         spec.Mkdir,
+		AttrTimeout:   time.Second, //Same defaults as glusterfs FUSE client
+		EntryTimeout:  time.Second,
+	}
+	for _, s := range u.Servers {
+		gs := gfapi.Server{Transport: transport, Host: s.Host, Port: s.Port}
+		if gs.Transport == "" {
+			gs.Transport = "tcp"
+		}
+		if gs.Port == 0 && !s.IsSocket() {
+			gs.Port = DefaultPort
+		}
+		c.Servers = append(c.Servers, gs)
+	}
+	for k, val := range u.Options {
+		if k != "log-level" {
+			return nil, fmt.Errorf("option %s of voluri is not supported by gfapi backend", k)
+		}
+		level, ok := gfapiLogLevels[strings.ToUpper(val)]
+		if !ok {
+			return nil, fmt.Errorf("unknown log-level %s", val)
+		}
+		c.LogLevel = level
+	}
+	if spec.SSL.IOEnabled() {
+		c.XlatorOptions["*"] = map[string]string{
+			"transport.socket.ssl-enabled":     "on",
+			"transport.socket.ssl-ca-list":     spec.SSL.CA,
+			"transport.socket.ssl-own-cert":    spec.SSL.Cert,
+			"transport.socket.ssl-private-key": spec.SSL.Key,
+		}
+	}
+	for k, dst := range map[string]*time.Duration{"attribute-timeout": &c.AttrTimeout, "entry-timeout": &c.EntryTimeout, "negative-timeout": &c.NegativeTimeout} {
+		if val := spec.Options[k]; val != "" {
+			n, _ := strconv.Atoi(val) //Already validated
+			*dst = time.Duration(n) * time.Second
+		}
+	}
+	c.DirectIO = spec.Options["direct-io-mode"] == "enable"
+	if val := spec.Options["read-ahead-page-count"]; val != "" {
+		c.XlatorOptions["*-read-ahead"] = map[string]string{"page-count": val}
+	}
+	if val := spec.Options["io-threads"]; val != "" {
+		c.XlatorOptions["*-io-threads"] = map[string]string{"thread-count": val}
+	}
+	return c, nil
+}
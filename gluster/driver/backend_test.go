@@ -0,0 +1,158 @@
+package driver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+type fakeBackend struct {
+	mounted map[string]MountSpec
+	fail    bool
+}
+
+func (b *fakeBackend) Mount(d *GlusterDriver, spec MountSpec) error {
+	if b.fail {
+		return fmt.Errorf("unable to mount %s", spec.Path)
+	}
+	if _, ok := b.mounted[spec.Path]; ok {
+		return fmt.Errorf("%s is already mounted", spec.Path)
+	}
+	b.mounted[spec.Path] = spec
+	return nil
+}
+
+func (b *fakeBackend) Unmount(d *GlusterDriver, path string) (bool, error) {
+	if _, ok := b.mounted[path]; !ok {
+		return false, nil
+	}
+	delete(b.mounted, path)
+	return true, nil
+}
+
+func TestBackends(t *testing.T) {
+	if b, err := backendOf(map[string]string{}); err != nil || b != backends["fuse"] {
+		t.Errorf("Expected fuse backend by default, got %v (%v)", b, err)
+	}
+	if _, err := backendOf(map[string]string{"backend": "nfs4"}); err == nil || !strings.Contains(err.Error(), "unknown backend") {
+		t.Errorf("Expected unknown backend to be refused, got %v", err)
+	}
+	if _, ok := backends["gfapi"]; !ok {
+		if _, err := backendOf(map[string]string{"backend": "gfapi"}); err == nil || !strings.Contains(err.Error(), "-tags gfapi") {
+			t.Errorf("Expected gfapi to need the gfapi build tag, got %v", err)
+		}
+	}
+	if err := ValidateOptions(map[string]string{"backend": "gfapi"}); err != nil {
+		t.Errorf("Expected gfapi to be a known backend, got %v", err)
+	}
+
+	fake := &fakeBackend{mounted: make(map[string]MountSpec)}
+	backends["fake"] = fake
+	defer delete(backends, "fake")
+	u, _ := ParseVolURI("node:vol")
+	if err := fake.Mount(nil, MountSpec{URI: u, Path: "/mnt/fake"}); err != nil {
+		t.Fatal(err)
+	}
+	d := &GlusterDriver{}
+	if err := d.UnmountPath("/mnt/fake"); err != nil || len(fake.mounted) != 0 {
+		t.Errorf("Expected path to be unmounted by the backend that mounted it, got %v (%v)", fake.mounted, err)
+	}
+}
+
+func TestBackendRemount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, backend string) { CfgFolder, DefaultBackend = cfg, backend }(CfgFolder, DefaultBackend)
+	CfgFolder = filepath.Join(dir, "cfg")
+	fake := &fakeBackend{mounted: make(map[string]MountSpec)}
+	backends["fake"] = fake
+	defer delete(backends, "fake")
+	DefaultBackend = "fake"
+	cmds, restore := captureCommands(nil)
+	defer restore()
+
+	d := Init(filepath.Join(dir, "root"), false)
+	if err := d.Create(&volume.CreateRequest{Name: "test", Options: map[string]string{"voluri": "node:vol"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := d.Mount(&volume.MountRequest{Name: "test", ID: "c1"}); err != nil {
+			t.Fatalf("Expected mount %d to succeed, got %v", i+1, err)
+		}
+		if err := d.Unmount(&volume.UnmountRequest{Name: "test", ID: "c1"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(fake.mounted) != 0 {
+			t.Errorf("Expected unmount to go through the backend, got %v", fake.mounted)
+		}
+	}
+	if len(*cmds) != 0 {
+		t.Errorf("Expected no fuse command, got %q", *cmds)
+	}
+}
+
+func TestRestoreMounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, proc, backend string) { CfgFolder, ProcMounts, DefaultBackend = cfg, proc, backend }(CfgFolder, ProcMounts, DefaultBackend)
+	CfgFolder = filepath.Join(dir, "cfg")
+	ProcMounts = filepath.Join(dir, "mounts")
+	if err := ioutil.WriteFile(ProcMounts, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeBackend{mounted: make(map[string]MountSpec)}
+	backends["fake"] = fake
+	defer delete(backends, "fake")
+	DefaultBackend = "fake"
+	root := filepath.Join(dir, "root")
+
+	d := Init(root, false)
+	if err := d.Create(&volume.CreateRequest{Name: "test", Options: map[string]string{"voluri": "node:vol"}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := d.Mount(&volume.MountRequest{Name: "test", ID: "c1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mounted = make(map[string]MountSpec) //Mounts served by the daemon are gone with it
+	d = Init(root, false)
+	restored, err := d.RestoreMounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.mounted[resp.Mountpoint]; !ok || len(restored) != 1 || restored[0] != resp.Mountpoint {
+		t.Errorf("Expected %s to be mounted again, got %v (%v)", resp.Mountpoint, restored, fake.mounted)
+	}
+	if v, err := d.Volume("test"); err != nil || v.Connections != 1 {
+		t.Errorf("Expected connections to be kept, got %v (%v)", v, err)
+	}
+
+	fake.mounted = make(map[string]MountSpec)
+	fake.fail = true
+	d = Init(root, false)
+	if restored, err := d.RestoreMounts(); err != nil || len(restored) != 0 {
+		t.Fatalf("Expected nothing to be restored, got %v (%v)", restored, err)
+	}
+	if v, err := Init(root, false).Volume("test"); err != nil || v.Connections != 0 {
+		t.Errorf("Expected connections to be reset and persisted, got %v (%v)", v, err)
+	}
+	fake.fail = false
+	if _, err := d.Mount(&volume.MountRequest{Name: "test", ID: "c2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.mounted[resp.Mountpoint]; !ok {
+		t.Errorf("Expected next use to mount %s, got %v", resp.Mountpoint, fake.mounted)
+	}
+}
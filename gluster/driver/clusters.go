@@ -0,0 +1,94 @@
+package driver
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+var (
+	//ClustersFile file defining named group of servers usable in voluri as @name (json, yaml or toml)
+	ClustersFile = CfgFolder + "clusters.yml"
+	//Clusters named group of servers defined in daemon config, overridden by the ones of ClustersFile
+	Clusters = map[string]Cluster{}
+)
+
+//Cluster named group of gluster servers
+type Cluster struct {
+	Servers   []string `mapstructure:"servers"`
+	SSLConfig `mapstructure:",squash"`
+}
+
+//loadClusters read cluster definitions, a missing file is equivalent to no cluster defined
+func loadClusters() (map[string]Cluster, error) {
+	clusters := make(map[string]Cluster, len(Clusters))
+	for name, c := range Clusters {
+		clusters[strings.ToLower(name)] = c
+	}
+	if _, err := os.Stat(ClustersFile); os.IsNotExist(err) {
+		return clusters, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(ClustersFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("unable to read clusters file %s: %v", ClustersFile, err)
+	}
+	for name, def := range v.AllSettings() {
+		var c Cluster
+		switch val := def.(type) {
+		case []interface{}:
+			for _, s := range val {
+				c.Servers = append(c.Servers, fmt.Sprint(s))
+			}
+		case map[string]interface{}:
+			if err := mapstructure.WeakDecode(val, &c); err != nil {
+				return nil, fmt.Errorf("unable to decode cluster %s: %v", name, err)
+			}
+		default:
+			return nil, fmt.Errorf("cluster %s must be a list of servers or a map", name)
+		}
+		if len(c.Servers) == 0 {
+			return nil, fmt.Errorf("cluster %s doesn't define any server", name)
+		}
+		clusters[name] = c
+	}
+	return clusters, nil
+}
+
+//clusterOf return the cluster referenced by the servers of voluri if any
+func clusterOf(u *VolURI, clusters map[string]Cluster) (*Cluster, error) {
+	for _, s := range u.Servers {
+		if !strings.HasPrefix(s.Host, "@") {
+			continue
+		}
+		c, ok := clusters[strings.ToLower(s.Host[1:])]
+		if !ok {
+			return nil, fmt.Errorf("cluster %s is not defined in %s", s.Host[1:], ClustersFile)
+		}
+		return &c, nil
+	}
+	return nil, nil
+}
+
+//expandClusters replace @name servers of voluri by the servers of the named cluster
+func (u *VolURI) expandClusters(clusters map[string]Cluster) error {
+	return u.mapServers(func(s Server) ([]Server, error) {
+		if !strings.HasPrefix(s.Host, "@") {
+			return []Server{s}, nil
+		}
+		c, ok := clusters[strings.ToLower(s.Host[1:])]
+		if !ok {
+			return nil, fmt.Errorf("cluster %s is not defined in %s", s.Host[1:], ClustersFile)
+		}
+		log.Debugf("Expanding cluster %s to %v", s.Host, c.Servers)
+		servers, err := parseServers(strings.Join(c.Servers, ","))
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %v", s.Host[1:], err)
+		}
+		return servers, nil
+	})
+}
@@ -0,0 +1,74 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-clusters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(f string) { ClustersFile = f }(ClustersFile)
+
+	ClustersFile = filepath.Join(dir, "missing.yml")
+	clusters, err := loadClusters()
+	if err != nil || len(clusters) != 0 {
+		t.Error("Expected missing file to define no cluster, got ", clusters, err)
+	}
+
+	ClustersFile = filepath.Join(dir, "clusters.yml")
+	content := `
+prod: [10.0.0.1, "[fd00::1]:24008"]
+secure:
+  servers: [node-1, node-2]
+  ssl: true
+  ssl-ca: /etc/ssl/gluster/ca.pem
+`
+	if err := ioutil.WriteFile(ClustersFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	clusters, err = loadClusters()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		value  string
+		result string
+		err    bool
+	}{
+		{"test:volume", "test:volume", false},
+		{"@prod:volume", "10.0.0.1,[fd00::1]:24008:volume", false},
+		{"@prod,test:volume", "10.0.0.1,[fd00::1]:24008,test:volume", false},
+		{"@secure:volume/sub", "node-1,node-2:volume/sub", false},
+		{"@unknown:volume", "", true},
+	}
+	for _, test := range tt {
+		u, err := ParseVolURI(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := ""
+		if err = u.expandClusters(clusters); err == nil {
+			r = u.String()
+		}
+		if (err != nil) != test.err || r != test.result {
+			t.Errorf("Expected to be '%v' (error: %v), got '%v' (%v)", test.result, test.err, r, err)
+		}
+	}
+
+	u, _ := ParseVolURI("@secure:volume")
+	c, err := clusterOf(u, clusters)
+	if err != nil || c == nil || !c.IOEnabled() || c.CA != "/etc/ssl/gluster/ca.pem" {
+		t.Error("Expected secure cluster with ssl settings, got ", c, err)
+	}
+	u, _ = ParseVolURI("test:volume")
+	if c, err := clusterOf(u, clusters); err != nil || c != nil {
+		t.Error("Expected no cluster, got ", c, err)
+	}
+}
@@ -0,0 +1,196 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var (
+	//KeystoreDir folder of the identities usable with the credentials option, one subfolder by identity containing glusterfs.ca, glusterfs.pem and glusterfs.key
+	KeystoreDir = CfgFolder + "keystore"
+	//CredentialsRunDir folder where credentials files are unpacked for glusterfs (should be a tmpfs)
+	CredentialsRunDir = "/run/docker-volume-gluster/credentials"
+	//Redacted value shown in place of credentials
+	Redacted = "<redacted>"
+
+	credentialNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-]*$`)
+	privateKeyArgRe  = regexp.MustCompile(`(ssl-private-key=)('\\''|[^'])*`)
+	//sensitiveOptions options whose value is redacted from logs and status
+	sensitiveOptions = []string{"credentials", "credentials-file", "ssl-key"}
+)
+
+//validateCredentials check the credentials and credentials-file options
+func validateCredentials(opts map[string]string) error {
+	name, file := opts["credentials"], opts["credentials-file"]
+	if name == "" && file == "" {
+		return nil
+	}
+	if name != "" && file != "" {
+		return fmt.Errorf("options credentials and credentials-file can't be used together")
+	}
+	for _, k := range []string{"ssl-ca", "ssl-cert", "ssl-key"} {
+		if opts[k] != "" {
+			return fmt.Errorf("option %s can't be used with credentials", k)
+		}
+	}
+	if name != "" && !credentialNameRe.MatchString(name) {
+		return fmt.Errorf("option credentials is not a valid identity name")
+	}
+	if file != "" && !filepath.IsAbs(file) {
+		return fmt.Errorf("option credentials-file need to be an absolute path")
+	}
+	return nil
+}
+
+//credentialsSSL return the certificates of the identity referenced by the volume options, ok is false if none is referenced.
+//A credentials file is only checked, its files are written by unpackCredentials when mounting
+func credentialsSSL(opts map[string]string) (c SSLConfig, ok bool, err error) {
+	if err := validateCredentials(opts); err != nil {
+		return c, false, err
+	}
+	if name := opts["credentials"]; name != "" {
+		dir := filepath.Join(KeystoreDir, name)
+		if _, err := os.Stat(dir); err != nil {
+			return c, true, fmt.Errorf("identity %s not found in keystore", name)
+		}
+		return identitySSL(dir), true, nil
+	}
+	if file := opts["credentials-file"]; file != "" {
+		if _, _, _, err := readCredentials(file); err != nil {
+			return c, true, err
+		}
+		return identitySSL(credentialsRunDir(file)), true, nil
+	}
+	return c, false, nil
+}
+
+//identitySSL return the certificates of an identity folder
+func identitySSL(dir string) SSLConfig {
+	enabled := true //Identities always encrypt I/O path
+	return SSLConfig{
+		CA:   filepath.Join(dir, "glusterfs.ca"),
+		Cert: filepath.Join(dir, "glusterfs.pem"),
+		Key:  filepath.Join(dir, "glusterfs.key"),
+		IO:   &enabled,
+	}
+}
+
+//credentialsRunDir return the folder where a credentials file is unpacked
+func credentialsRunDir(file string) string {
+	sum := sha256.Sum256([]byte(file))
+	return filepath.Join(CredentialsRunDir, hex.EncodeToString(sum[:8]))
+}
+
+//readCredentials split a PEM bundle (private key, client certificate then CA certificates) in the parts needed by glusterfs
+func readCredentials(file string) (key, cert, ca []byte, err error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to read credentials file: %v", err)
+	}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		switch {
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			key = pem.EncodeToMemory(block)
+		case block.Type == "CERTIFICATE" && cert == nil:
+			cert = pem.EncodeToMemory(block)
+		case block.Type == "CERTIFICATE":
+			ca = append(ca, pem.EncodeToMemory(block)...)
+		}
+	}
+	if key == nil || cert == nil || ca == nil {
+		return nil, nil, nil, fmt.Errorf("credentials file need to contain a private key, the client certificate and CA certificates")
+	}
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		return nil, nil, nil, fmt.Errorf("client certificate of credentials file doesn't match its private key")
+	}
+	return key, cert, ca, nil
+}
+
+//unpackCredentials write the files of the credentials file of the volume options for glusterfs, only when mounting
+func unpackCredentials(opts map[string]string) error {
+	file := opts["credentials-file"]
+	if file == "" {
+		return nil
+	}
+	key, cert, ca, err := readCredentials(file)
+	if err != nil {
+		return err
+	}
+	c := identitySSL(credentialsRunDir(file))
+	if err := os.MkdirAll(filepath.Dir(c.Key), 0700); err != nil {
+		return err
+	}
+	for path, content := range map[string][]byte{c.CA: ca, c.Cert: cert, c.Key: key} {
+		if cur, err := ioutil.ReadFile(path); err == nil && bytes.Equal(cur, content) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, content, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//releaseCredentials delete the unpacked credentials file of the volume if no other mounted or published volume use it (lock need to be hold)
+func (d *GlusterDriver) releaseCredentials(v *GlusterVolume) {
+	file := v.withDefaults().Options["credentials-file"]
+	if file == "" {
+		return
+	}
+	for _, o := range d.volumes {
+		if o != v && o.Connections > 0 && o.withDefaults().Options["credentials-file"] == file {
+			return
+		}
+	}
+	for _, o := range d.published { //Entry of the released path is already removed
+		if o.withDefaults().Options["credentials-file"] == file {
+			return
+		}
+	}
+	if err := os.RemoveAll(credentialsRunDir(file)); err != nil {
+		log.Warnf("Unable to delete unpacked credentials: %v", err)
+	}
+}
+
+//redactOptions return a copy of opts with the value of sensitive options redacted
+func redactOptions(opts map[string]string) map[string]string {
+	if opts == nil {
+		return nil
+	}
+	res := make(map[string]string, len(opts))
+	for k, val := range opts {
+		res[k] = val
+	}
+	for _, k := range sensitiveOptions {
+		if _, ok := res[k]; ok {
+			res[k] = Redacted
+		}
+	}
+	return res
+}
+
+//String describe the volume in logs with sensitive options redacted
+func (v *GlusterVolume) String() string {
+	return fmt.Sprintf("&{%s %s %d %v %v}", v.VolumeURI, v.Mount, v.Connections, redactOptions(v.Options), v.Containers)
+}
+
+//redactCmd return cmd with the private key given to glusterfs redacted
+func redactCmd(cmd string) string {
+	return privateKeyArgRe.ReplaceAllString(cmd, "${1}"+Redacted)
+}
@@ -0,0 +1,140 @@
+package driver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, keystore, run string) { CfgFolder, KeystoreDir, CredentialsRunDir = cfg, keystore, run }(CfgFolder, KeystoreDir, CredentialsRunDir)
+	CfgFolder = filepath.Join(dir, "cfg")
+	KeystoreDir = filepath.Join(dir, "keystore")
+	CredentialsRunDir = filepath.Join(dir, "run")
+	cmds, restore := captureCommands(nil)
+	defer restore()
+
+	certs := writeTestCerts(t, dir)
+	var bundle []byte
+	for _, f := range []string{certs.Key, certs.Cert, certs.CA} {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle = append(bundle, b...)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "tenant.pem"), bundle, 0600); err != nil {
+		t.Fatal(err)
+	}
+	identity := filepath.Join(KeystoreDir, "tenant")
+	if err := os.MkdirAll(identity, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for src, dst := range map[string]string{certs.CA: "glusterfs.ca", certs.Cert: "glusterfs.pem", certs.Key: "glusterfs.key"} {
+		if err := os.Rename(src, filepath.Join(identity, dst)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, opts := range []map[string]string{
+		{"credentials": "tenant", "credentials-file": "/run/secrets/tenant"},
+		{"credentials": "../tenant"},
+		{"credentials-file": "tenant.pem"},
+		{"credentials": "tenant", "ssl-key": "/etc/ssl/key.pem"},
+	} {
+		if err := ValidateOptions(opts); err == nil {
+			t.Errorf("Expected %v to be refused", opts)
+		}
+	}
+
+	d := Init(filepath.Join(dir, "root"), true)
+	unpacked := credentialsRunDir(filepath.Join(dir, "tenant.pem"))
+	for _, test := range []struct {
+		name string
+		opts map[string]string
+	}{
+		{"keystore", map[string]string{"voluri": "node:vol", "credentials": "tenant"}},
+		{"file", map[string]string{"voluri": "node:vol", "credentials-file": filepath.Join(dir, "tenant.pem")}},
+	} {
+		name := test.name
+		if err := d.Create(&volume.CreateRequest{Name: name, Options: test.opts}); err != nil {
+			t.Fatalf("Expected %s to be created, got %v", name, err)
+		}
+		if _, err := os.Stat(unpacked); err == nil {
+			t.Errorf("Expected credentials file to not be unpacked before mount of %s", name)
+		}
+		*cmds = nil
+		if _, err := d.Mount(&volume.MountRequest{Name: name, ID: "c1"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(*cmds) != 1 || !strings.Contains((*cmds)[0], "ssl-private-key=") || !strings.Contains((*cmds)[0], "glusterfs.key") {
+			t.Errorf("Expected %s to be mounted with its identity, got %q", name, *cmds)
+		}
+		if strings.Contains(redactCmd((*cmds)[0]), "glusterfs.key") {
+			t.Errorf("Expected private key to be redacted from %s", redactCmd((*cmds)[0]))
+		}
+		if s := d.volumes[name].GetStatus()["credentials"]; s != Redacted {
+			t.Errorf("Expected credentials of %s to be redacted in status, got %v", name, s)
+		}
+		if s := fmt.Sprintf("%v", d.volumes[name]); strings.Contains(s, "tenant") {
+			t.Errorf("Expected credentials of %s to be redacted in logs, got %s", name, s)
+		}
+	}
+	if _, err := os.Stat(unpacked); err != nil {
+		t.Errorf("Expected credentials file to be unpacked: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(CfgFolder, "persistence.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "PRIVATE KEY") || strings.Contains(string(b), CredentialsRunDir) {
+		t.Errorf("Expected credentials to not be persisted, got %s", b)
+	}
+
+	if err := d.Unmount(&volume.UnmountRequest{Name: "file", ID: "c1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(unpacked); !os.IsNotExist(err) {
+		t.Errorf("Expected unpacked credentials to be deleted on unmount, got %v", err)
+	}
+	if err := d.Provision(d.volumes["file"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(&volume.RemoveRequest{Name: "file"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(unpacked); !os.IsNotExist(err) {
+		t.Errorf("Expected unpacked credentials to be deleted on remove, got %v", err)
+	}
+
+	m := NewMounter()
+	v := &GlusterVolume{VolumeURI: "node:vol", Options: map[string]string{"credentials-file": filepath.Join(dir, "tenant.pem")}}
+	for _, p := range []string{"p1", "p2"} {
+		if err := m.MountVolume(v, filepath.Join(dir, p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.ReleaseCredentials(filepath.Join(dir, "p1"))
+	if _, err := os.Stat(unpacked); err != nil {
+		t.Errorf("Expected credentials file to be kept while still published: %v", err)
+	}
+	m.ReleaseCredentials(filepath.Join(dir, "p2"))
+	if _, err := os.Stat(unpacked); !os.IsNotExist(err) {
+		t.Errorf("Expected unpacked credentials to be deleted on unpublish, got %v", err)
+	}
+
+	if err := d.Create(&volume.CreateRequest{Name: "unknown", Options: map[string]string{"voluri": "node:vol", "credentials": "other"}}); err == nil {
+		t.Error("Expected identity missing from keystore to be refused")
+	}
+}
@@ -21,6 +21,16 @@ var (
 	CfgVersion = 1
 	//CfgFolder config folder
 	CfgFolder = "/etc/docker-volumes/gluster/"
+	//AutoCreateVolURI voluri under which unknown volumes requested by Get/Path/Mount are created as a subdir named after the volume (disabled if empty)
+	AutoCreateVolURI = ""
+	//AutoCreatePattern regexp that name of a volume need to match to be auto created
+	AutoCreatePattern = ".*"
+	//ProcMounts file listing mounted filesystems
+	ProcMounts = "/proc/mounts"
+	//DefaultOptions options applied at mount time to volumes that don't define them
+	DefaultOptions = map[string]string{}
+	//RecoveryMode accept Create of volumes whose mountpoint already exist (replayed by docker after loss of persistence)
+	RecoveryMode = false
 )
 
 type GlusterMountpoint struct {
@@ -41,9 +51,12 @@ func (d *GlusterMountpoint) SetConnections(n int) {
 }
 
 type GlusterVolume struct {
-	VolumeURI   string `json:"voluri"`
-	Mount       string `json:"mount"`
-	Connections int    `json:"connections"`
+	VolumeURI   string            `json:"voluri" mapstructure:"voluri"` //Persistence is decoded by viper with mapstructure tags
+	Mount       string            `json:"mount"`
+	Connections int               `json:"connections"`
+	Options     map[string]string `json:"options,omitempty"`
+	Containers  []string          `json:"containers,omitempty"`  //Mount ids having their own subdir (per-container option)
+	Initialized bool              `json:"initialized,omitempty"` //Root options were applied after the first mount
 }
 
 func (v *GlusterVolume) GetMount() string {
@@ -62,10 +75,73 @@ func (v *GlusterVolume) SetConnections(n int) {
 	v.Connections = n
 }
 
+//VolURI return the parsed voluri of the volume completed by the volume options
+func (v *GlusterVolume) VolURI() (*VolURI, error) {
+	u, err := ParseVolURI(v.VolumeURI)
+	if err != nil {
+		return nil, err
+	}
+	if t := v.Options["transport"]; t != "" {
+		if u.Transport != "" && u.Transport != t {
+			return nil, fmt.Errorf("transport option %s conflict with voluri transport %s", t, u.Transport)
+		}
+		u.Transport = t
+	}
+	return u, nil
+}
+
+//SSL return the ssl settings of the volume completed by the cluster ones (if any) and daemon defaults
+func (v *GlusterVolume) SSL(c *Cluster) (SSLConfig, error) {
+	s, err := sslFromOptions(v.Options)
+	if err != nil {
+		return s, err
+	}
+	if cred, ok, err := credentialsSSL(v.Options); err != nil {
+		return s, err
+	} else if ok {
+		cred.Mgmt = s.Mgmt
+		s = cred
+	}
+	if c != nil {
+		s = s.merge(c.SSLConfig)
+	}
+	return s.merge(DefaultSSL), nil
+}
+
+//ReadOnly return true if the volume is mounted read-only
+func (v *GlusterVolume) ReadOnly() bool {
+	ro, _ := parseBoolOpt(v.Options, "ro")
+	return ro
+}
+
 func (v *GlusterVolume) GetStatus() map[string]interface{} {
-	return map[string]interface{}{
-		"TODO": "List",
+	mode := "rw"
+	if v.ReadOnly() {
+		mode = "ro"
+	}
+	status := map[string]interface{}{
+		"voluri": v.VolumeURI,
+		"mode":   mode,
+	}
+	if v.Options["credentials"] != "" || v.Options["credentials-file"] != "" {
+		status["credentials"] = Redacted
+	}
+	c := v.withDefaults()
+	if name := c.Options["profile"]; name != "" {
+		status["profile"] = name
 	}
+	if t := tuning(c.Options); len(t) > 0 {
+		status["tuning"] = t
+	}
+	if perContainer(c.Options) {
+		cleanup := c.Options["per-container-cleanup"]
+		if cleanup == "" {
+			cleanup = "keep"
+		}
+		status["per-container"] = cleanup
+		status["containers"] = len(v.Containers)
+	}
+	return status
 }
 
 //GlusterDriver the global driver responding to call
@@ -76,6 +152,8 @@ type GlusterDriver struct {
 	persitence    *viper.Viper
 	volumes       map[string]*GlusterVolume
 	mounts        map[string]*GlusterMountpoint
+	registry      Registry
+	published     map[string]*GlusterVolume //Volumes mounted with MountVolume by path, to release their credentials
 }
 
 func (d *GlusterDriver) GetVolumes() map[string]common.Volume {
@@ -101,6 +179,25 @@ func (d *GlusterDriver) GetLock() *sync.RWMutex {
 //Init start all needed deps and serve response to API call
 func Init(root string, mountUniqName bool) *GlusterDriver {
 	log.Debugf("Init gluster driver at %s, UniqName: %v", root, mountUniqName)
+	d := Load(root, mountUniqName)
+	if mountUniqName {
+		if err := d.migrateMountNames(); err != nil {
+			log.Warnf("Unable to migrate mountpoints to current naming: %v", err)
+		}
+	}
+	if RegistryVolURI != "" || RegistryDir != "" {
+		reg, err := d.openRegistry()
+		if err != nil {
+			log.Errorf("Unable to open shared registry, volumes will only be known locally: %v", err)
+		} else {
+			d.registry = reg
+		}
+	}
+	return d
+}
+
+//Load return the driver with the state of the persistence file without changing anything (no mountpoint migration nor shared registry), for offline inspection
+func Load(root string, mountUniqName bool) *GlusterDriver {
 	d := &GlusterDriver{
 		root:          root,
 		mountUniqName: mountUniqName,
@@ -113,54 +210,81 @@ func Init(root string, mountUniqName bool) *GlusterDriver {
 	d.persitence.SetConfigName("persistence")
 	d.persitence.SetConfigType("json")
 	d.persitence.AddConfigPath(CfgFolder)
-	if err := d.persitence.ReadInConfig(); err != nil { // Handle errors reading the config file
-		log.Warn("No persistence file found, I will start with a empty list of volume.", err)
-	} else {
-		log.Debug("Retrieving volume list from persistence file.")
-
-		var version int
-		err := d.persitence.UnmarshalKey("version", &version)
-		if err != nil || version != CfgVersion {
-			log.Warn("Unable to decode version of persistence, %v", err)
-			d.volumes = make(map[string]*GlusterVolume)
-			d.mounts = make(map[string]*GlusterMountpoint)
-		} else { //We have the same version
-			err := d.persitence.UnmarshalKey("volumes", &d.volumes)
-			if err != nil {
-				log.Warn("Unable to decode into struct -> start with empty list, %v", err)
-				d.volumes = make(map[string]*GlusterVolume)
-			}
-			err = d.persitence.UnmarshalKey("mounts", &d.mounts)
-			if err != nil {
-				log.Warn("Unable to decode into struct -> start with empty list, %v", err)
-				d.mounts = make(map[string]*GlusterMountpoint)
-			}
-		}
+	if err := d.loadConfig(); err != nil {
+		log.Warn(err)
 	}
 	return d
 }
 
+//NewMounter return a driver without persistence nor shared registry, for callers like CSI that only mount volumes on demand and must not share the daemon state
+func NewMounter() *GlusterDriver {
+	return &GlusterDriver{
+		volumes: make(map[string]*GlusterVolume),
+		mounts:  make(map[string]*GlusterMountpoint),
+	}
+}
+
+//loadConfig read volumes and mounts from persistence file (lock need to be hold or driver not yet used)
+func (d *GlusterDriver) loadConfig() error {
+	d.volumes = make(map[string]*GlusterVolume)
+	d.mounts = make(map[string]*GlusterMountpoint)
+	if err := d.persitence.ReadInConfig(); err != nil { // Handle errors reading the config file
+		return fmt.Errorf("No persistence file found, I will start with a empty list of volume. %v", err)
+	}
+	log.Debug("Retrieving volume list from persistence file.")
+
+	var version int
+	err := d.persitence.UnmarshalKey("version", &version)
+	if err != nil || version != CfgVersion {
+		return fmt.Errorf("Unable to decode version of persistence, %v", err)
+	}
+	//We have the same version
+	if err := d.persitence.UnmarshalKey("volumes", &d.volumes); err != nil {
+		d.volumes = make(map[string]*GlusterVolume)
+		return fmt.Errorf("Unable to decode into struct -> start with empty list, %v", err)
+	}
+	if err := d.persitence.UnmarshalKey("mounts", &d.mounts); err != nil {
+		d.mounts = make(map[string]*GlusterMountpoint)
+		return fmt.Errorf("Unable to decode into struct -> start with empty list, %v", err)
+	}
+	return nil
+}
+
 //Create create and init the requested volume
 func (d *GlusterDriver) Create(r *volume.CreateRequest) error {
-	log.Debugf("Entering Create: name: %s, options %v", r.Name, r.Options)
+	log.Debugf("Entering Create: name: %s, options %v", r.Name, redactOptions(r.Options))
 
 	if r.Options == nil || r.Options["voluri"] == "" {
 		return fmt.Errorf("voluri option required")
 	}
 	r.Options["voluri"] = strings.Trim(r.Options["voluri"], "\"")
-	if !isValidURI(r.Options["voluri"]) {
-		return fmt.Errorf("voluri option is malformated")
+	volURI, err := ExpandVolURI(r.Options["voluri"], r.Name)
+	if err != nil {
+		return err
+	}
+	if volURI != r.Options["voluri"] {
+		log.Debugf("Voluri of %s expanded to %s", r.Name, volURI)
+	}
+	opts := make(map[string]string, len(r.Options))
+	for k, val := range r.Options {
+		if k != "voluri" {
+			opts[k] = val
+		}
+	}
+	v := &GlusterVolume{
+		VolumeURI:   volURI,
+		Connections: 0,
+		Options:     opts,
 	}
-
 	d.GetLock().Lock()
 	defer d.GetLock().Unlock()
 
-	v := &GlusterVolume{
-		VolumeURI:   r.Options["voluri"],
-		Mount:       getMountName(d, r),
-		Connections: 0,
+	if err := v.Validate(); err != nil { //Under lock as defaults, profiles and clusters can be reloaded
+		return err
 	}
 
+	v.Mount = getMountName(d, r.Name, v)
+
 	if _, ok := d.mounts[v.Mount]; !ok { //This mountpoint doesn't allready exist -> create it
 		m := &GlusterMountpoint{
 			Path:        filepath.Join(d.root, v.Mount),
@@ -180,40 +304,166 @@ func (d *GlusterDriver) Create(r *volume.CreateRequest) error {
 			return err
 		}
 		if !isempty {
-			return fmt.Errorf("%v already exist and is not empty !", m.Path)
+			if !RecoveryMode {
+				return fmt.Errorf("%v already exist and is not empty !", m.Path)
+			}
+			if err := adoptMountpoint(m); err != nil {
+				return err
+			}
 		}
 		d.mounts[v.Mount] = m
 	}
 
+	if m := d.mounts[v.Mount]; m.Connections > 0 && d.volumeOfMount(v.Mount) == nil { //Adopted in recovery mode
+		common.SetN(m.Connections, v)
+	}
 	d.volumes[r.Name] = v
 	log.Debugf("Volume Created: %v", v)
 	if err := d.SaveConfig(); err != nil {
 		return err
 	}
+	if d.registry != nil {
+		return d.registry.Put(r.Name, v)
+	}
 	return nil
 }
 
+//withDefaults return a copy of the volume completed by DefaultOptions and the options of its profile
+func (v *GlusterVolume) withDefaults() *GlusterVolume {
+	if len(DefaultOptions) == 0 && v.Options["profile"] == "" {
+		return v
+	}
+	c := *v
+	c.Options = make(map[string]string, len(v.Options)+len(DefaultOptions))
+	for k, val := range DefaultOptions {
+		c.Options[k] = val
+	}
+	for k, val := range v.Options {
+		c.Options[k] = val
+	}
+	if p, err := lookupProfile(c.Options["profile"]); err == nil {
+		for k, val := range p {
+			if _, ok := c.Options[k]; !ok {
+				c.Options[k] = val
+			}
+		}
+	}
+	return &c
+}
+
+//ValidateOptions check the value of known volume options
+func ValidateOptions(opts map[string]string) error {
+	if _, err := parseBoolOpt(opts, "ro"); err != nil {
+		return err
+	}
+	if _, err := parseRootOptions(opts); err != nil {
+		return err
+	}
+	if err := validateClientOptions(opts); err != nil {
+		return err
+	}
+	if err := validateBackend(opts); err != nil {
+		return err
+	}
+	if err := validatePerContainer(opts); err != nil {
+		return err
+	}
+	if err := validateCredentials(opts); err != nil {
+		return err
+	}
+	_, err := sslFromOptions(opts)
+	return err
+}
+
+//Validate check voluri and options of the volume
+func (v *GlusterVolume) Validate() error {
+	u, err := v.VolURI()
+	if err != nil {
+		return fmt.Errorf("voluri option is malformated: %v", err)
+	}
+	if _, err := u.Args(); err != nil {
+		return fmt.Errorf("voluri option is malformated: %v", err)
+	}
+	if err := ValidateOptions(v.Options); err != nil {
+		return err
+	}
+	if name := v.Options["profile"]; name != "" {
+		if _, err := lookupProfile(name); err != nil {
+			return err
+		}
+	}
+	if _, err := backendOf(v.withDefaults().Options); err != nil {
+		return err
+	}
+
+	clusters, err := loadClusters()
+	if err != nil {
+		return err
+	}
+	cluster, err := clusterOf(u, clusters)
+	if err != nil {
+		log.Warnf("%v, it need to be defined before mounting", err)
+	}
+	ssl, err := v.SSL(cluster)
+	if err != nil {
+		return err
+	}
+	if v.Options["credentials-file"] != "" {
+		return nil //Bundle already checked, its files are only written when mounting
+	}
+	return ssl.Validate()
+}
+
 //List volumes handled by these driver
 func (d *GlusterDriver) List() (*volume.ListResponse, error) {
-	return common.List(d)
+	l, err := common.List(d)
+	if err != nil || d.registry == nil {
+		return l, err
+	}
+	shared, err := d.registry.List()
+	if err != nil {
+		log.Warnf("Unable to list shared registry: %v", err)
+		return l, nil
+	}
+	d.GetLock().RLock()
+	defer d.GetLock().RUnlock()
+	for name, v := range shared {
+		if _, ok := d.volumes[name]; !ok { //Not yet created on this host
+			l.Volumes = append(l.Volumes, &volume.Volume{Name: name, Status: v.GetStatus()})
+		}
+	}
+	return l, nil
 }
 
 //Get get info on the requested volume
 func (d *GlusterDriver) Get(r *volume.GetRequest) (*volume.GetResponse, error) {
-	v, m, err := common.Get(d, r.Name)
-	if err != nil {
+	if err := d.ensureVolume(r.Name); err != nil {
 		return nil, err
 	}
-	return &volume.GetResponse{Volume: &volume.Volume{Name: r.Name, Status: v.GetStatus(), Mountpoint: m.GetPath()}}, nil
+	d.GetLock().RLock()
+	defer d.GetLock().RUnlock() //Status read defaults and profiles that can be reloaded
+	v, ok := d.volumes[r.Name]
+	if !ok {
+		return nil, fmt.Errorf("volume %s not found", r.Name)
+	}
+	m, ok := d.mounts[v.Mount]
+	if !ok {
+		return nil, fmt.Errorf("mount %s not found", v.Mount)
+	}
+	return &volume.GetResponse{Volume: &volume.Volume{Name: r.Name, Status: v.GetStatus(), Mountpoint: m.Path}}, nil
 }
 
 //Remove remove the requested volume
 func (d *GlusterDriver) Remove(r *volume.RemoveRequest) error {
-	return common.Remove(d, r.Name)
+	log.Debugf("Entering Remove: name: %s", r.Name)
+	return d.RemoveVolume(r.Name, false)
 }
 
 //Path get path of the requested volume
 func (d *GlusterDriver) Path(r *volume.PathRequest) (*volume.PathResponse, error) {
+	if err := d.ensureVolume(r.Name); err != nil {
+		return nil, err
+	}
 	_, m, err := common.Get(d, r.Name)
 	if err != nil {
 		return nil, err
@@ -225,6 +475,16 @@ func (d *GlusterDriver) Path(r *volume.PathRequest) (*volume.PathResponse, error
 func (d *GlusterDriver) Mount(r *volume.MountRequest) (*volume.MountResponse, error) {
 	log.Debugf("Entering Mount: %v", r)
 
+	if err := d.ensureVolume(r.Name); err != nil {
+		return nil, err
+	}
+	if d.isPerContainer(r.Name) {
+		path, err := d.mountContainer(r.Name, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &volume.MountResponse{Mountpoint: path}, nil
+	}
 	v, m, err := common.MountExist(d, r.Name)
 	if err != nil {
 		return nil, err
@@ -236,10 +496,9 @@ func (d *GlusterDriver) Mount(r *volume.MountRequest) (*volume.MountResponse, er
 	d.GetLock().Lock()
 	defer d.GetLock().Unlock()
 
-	cmd := fmt.Sprintf("glusterfs %s %s", parseVolURI(v.GetRemote()), m.GetPath())
 	//cmd := fmt.Sprintf("/usr/bin/mount -t glusterfs %s %s", v.VolumeURI, m.Path)
 	//TODO fuseOpts   /usr/bin/mount -t glusterfs v.VolumeURI -o fuseOpts v.Mountpoint
-	if err := d.RunCmd(cmd); err != nil {
+	if err := d.mountVolume(d.volumes[r.Name], m.GetPath()); err != nil {
 		return nil, err
 	}
 	//time.Sleep(3 * time.Second)
@@ -247,9 +506,161 @@ func (d *GlusterDriver) Mount(r *volume.MountRequest) (*volume.MountResponse, er
 	return &volume.MountResponse{Mountpoint: m.GetPath()}, d.SaveConfig()
 }
 
+//MountVolume mount the volume on path
+func (d *GlusterDriver) MountVolume(v *GlusterVolume, path string) error {
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	if err := d.mountVolume(v, path); err != nil {
+		d.releaseCredentials(v)
+		return err
+	}
+	if d.published == nil {
+		d.published = make(map[string]*GlusterVolume)
+	}
+	d.published[path] = v
+	return nil
+}
+
+//ReleaseCredentials delete the unpacked credentials of the volume mounted on path with MountVolume if no other volume use them, to call once path is unmounted
+func (d *GlusterDriver) ReleaseCredentials(path string) {
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	v, ok := d.published[path]
+	if !ok {
+		return
+	}
+	delete(d.published, path)
+	d.releaseCredentials(v)
+}
+
+//mountVolume mount the volume on path, root options are applied at the first mount only (lock need to be hold)
+func (d *GlusterDriver) mountVolume(orig *GlusterVolume, path string) error {
+	v := orig.withDefaults()
+	if name := v.Options["profile"]; name != "" {
+		if _, err := lookupProfile(name); err != nil {
+			return err
+		}
+	}
+	b, err := backendOf(v.Options)
+	if err != nil {
+		return err
+	}
+	u, ssl, err := d.resolve(v)
+	if err != nil {
+		return err
+	}
+	root, err := parseRootOptions(v.Options)
+	if err != nil {
+		return err
+	}
+	spec := MountSpec{URI: u, SSL: ssl, Options: v.Options, ReadOnly: v.ReadOnly(), Mkdir: root.Mkdir, Path: path}
+	if err := b.Mount(d, spec); err != nil {
+		return err
+	}
+	if orig.Initialized {
+		return nil
+	}
+	if err := root.apply(path); err != nil {
+		if uerr := d.UnmountPath(path); uerr != nil {
+			log.Warnf("Unable to unmount %s: %v", path, uerr)
+		}
+		return err
+	}
+	orig.Initialized = true
+	return nil
+}
+
+//UnmountPath unmount the volume mounted on path by the backend that mounted it
+func (d *GlusterDriver) UnmountPath(path string) error {
+	for name, b := range backends {
+		if name == "fuse" {
+			continue
+		}
+		if handled, err := b.Unmount(d, path); handled {
+			return err
+		}
+	}
+	_, err := backends["fuse"].Unmount(d, path)
+	return err
+}
+
+//Provision create the subdir of the volume on the gluster volume if it doesn't exist
+func (d *GlusterDriver) Provision(v *GlusterVolume) error {
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	u, ssl, err := d.resolve(v.withDefaults())
+	if err != nil {
+		return err
+	}
+	if u.Subdir == "" {
+		return nil
+	}
+	return d.mkdirSubdir(u, ssl.cmdArgs())
+}
+
+//resolve expand the servers of the volume, validate and stage its certificates and return its voluri and ssl settings
+func (d *GlusterDriver) resolve(v *GlusterVolume) (*VolURI, SSLConfig, error) {
+	var ssl SSLConfig
+	clusters, err := loadClusters()
+	if err != nil {
+		return nil, ssl, err
+	}
+	u, err := v.VolURI()
+	if err != nil {
+		return nil, ssl, err
+	}
+	cluster, err := clusterOf(u, clusters)
+	if err != nil {
+		return nil, ssl, err
+	}
+	ssl, err = v.SSL(cluster)
+	if err != nil {
+		return nil, ssl, err
+	}
+	if err := unpackCredentials(v.Options); err != nil {
+		return nil, ssl, err
+	}
+	if err := ssl.Validate(); err != nil {
+		return nil, ssl, err
+	}
+	if err := ssl.stage(d.mgmtSSLInUse(clusters)); err != nil {
+		return nil, ssl, err
+	}
+	if err := u.expandClusters(clusters); err != nil {
+		return nil, ssl, err
+	}
+	if err := u.expandSRV(); err != nil {
+		return nil, ssl, err
+	}
+	return u, ssl, nil
+}
+
 //Unmount unmount the requested volume
 func (d *GlusterDriver) Unmount(r *volume.UnmountRequest) error {
-	return common.Unmount(d, r.Name)
+	if d.isPerContainer(r.Name) {
+		return d.unmountContainer(r.Name, r.ID)
+	}
+	log.Debugf("Entering Unmount: %v", r)
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	v, ok := d.volumes[r.Name]
+	if !ok {
+		return fmt.Errorf("volume %s not found", r.Name)
+	}
+	m, ok := d.mounts[v.Mount]
+	if !ok {
+		return fmt.Errorf("mount %s not found", v.Mount)
+	}
+	if m.Connections <= 1 {
+		if err := d.UnmountPath(m.Path); err != nil { //Let the backend that mounted it release it
+			return err
+		}
+		common.SetN(0, m, v)
+		d.releaseCredentials(v)
+	} else {
+		common.AddN(-1, m, v)
+	}
+	return d.SaveConfig()
 }
 
 //Capabilities Send capabilities of the local driver
@@ -1,7 +1,12 @@
 package driver
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
 )
 
 func TestInit(t *testing.T) {
@@ -15,3 +20,120 @@ func TestInit(t *testing.T) {
 			}
 	*/
 }
+
+func TestWithDefaults(t *testing.T) {
+	defer func(opts map[string]string) { DefaultOptions = opts }(DefaultOptions)
+	v := &GlusterVolume{VolumeURI: "node:vol", Options: map[string]string{"uid": "1000"}}
+	if v.withDefaults() != v {
+		t.Error("Expected volume to be unchanged without defaults")
+	}
+	DefaultOptions = map[string]string{"uid": "0", "gid": "100"}
+	c := v.withDefaults()
+	if c.Options["uid"] != "1000" || c.Options["gid"] != "100" {
+		t.Errorf("Expected volume options to override defaults, got %v", c.Options)
+	}
+	if _, ok := v.Options["gid"]; ok {
+		t.Error("Expected original volume to not be modified")
+	}
+}
+
+func TestVolumeStatus(t *testing.T) {
+	v := &GlusterVolume{VolumeURI: "test:volume"}
+	if s := v.GetStatus(); s["mode"] != "rw" || s["voluri"] != "test:volume" {
+		t.Error("Expected read-write status, got ", s)
+	}
+	v.Options = map[string]string{"ro": "true"}
+	if s := v.GetStatus(); s["mode"] != "ro" {
+		t.Error("Expected read-only status, got ", s)
+	}
+}
+
+func TestAutoCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-autocreate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, voluri, pattern string) { CfgFolder, AutoCreateVolURI, AutoCreatePattern = cfg, voluri, pattern }(CfgFolder, AutoCreateVolURI, AutoCreatePattern)
+	CfgFolder = filepath.Join(dir, "cfg")
+
+	d := Init(filepath.Join(dir, "root"), false)
+	if _, err := d.Get(&volume.GetRequest{Name: "data-1"}); err == nil {
+		t.Error("Expected unknown volume to not be found without auto create")
+	}
+
+	AutoCreateVolURI, AutoCreatePattern = "@prod:docker", "^data-"
+	if _, err := d.Get(&volume.GetRequest{Name: "other"}); err == nil {
+		t.Error("Expected volume not matching pattern to not be found")
+	}
+	r, err := d.Get(&volume.GetRequest{Name: "data-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Volume.Status["voluri"] != "@prod:docker/data-1" {
+		t.Error("Expected volume to be created on @prod:docker/data-1, got ", r.Volume.Status["voluri"])
+	}
+	if d.volumes["data-1"].Options["mkdir"] != "true" {
+		t.Error("Expected auto created volume to create its subdir")
+	}
+}
+
+func TestGetDuringReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string, opts map[string]string) { CfgFolder, DefaultOptions = cfg, opts }(CfgFolder, DefaultOptions)
+	CfgFolder = filepath.Join(dir, "cfg")
+
+	d := Init(filepath.Join(dir, "root"), false)
+	if err := d.Create(&volume.CreateRequest{Name: "test", Options: map[string]string{"voluri": "node:vol"}}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() { //As applyRuntimeConfig on SIGHUP (run with -race)
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			d.GetLock().Lock()
+			DefaultOptions = map[string]string{"profile": "largefile"}
+			d.GetLock().Unlock()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := d.Get(&volume.GetRequest{Name: "test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestLoadReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-load")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string) { CfgFolder = cfg }(CfgFolder)
+	CfgFolder = filepath.Join(dir, "cfg")
+
+	root := filepath.Join(dir, "root")
+	if err := Init(root, false).Create(&volume.CreateRequest{Name: "test", Options: map[string]string{"voluri": "node:vol"}}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := ioutil.ReadFile(filepath.Join(CfgFolder, "persistence.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Load(root, true) //Init would migrate mountpoint to uniq naming
+	if _, err := d.Volume("test"); err != nil {
+		t.Errorf("Expected volume to be loaded, got %v", err)
+	}
+	after, err := ioutil.ReadFile(filepath.Join(CfgFolder, "persistence.json"))
+	if err != nil || string(after) != string(before) {
+		t.Errorf("Expected persistence file to be unchanged, got %s (%v)", after, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "test")); err != nil {
+		t.Errorf("Expected mountpoint to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package driver
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+//ExportVersion version of the export format
+const ExportVersion = 1
+
+const (
+	//ConflictSkip keep the existing volume when importing a volume with the same name
+	ConflictSkip = "skip"
+	//ConflictOverwrite replace the existing volume (if not used) when importing a volume with the same name
+	ConflictOverwrite = "overwrite"
+	//ConflictRename import the volume under a free name (name-1, name-2, ...)
+	ConflictRename = "rename"
+)
+
+//Export exported volume definitions
+//
+//	{"version": 1, "volumes": [{"name": "test", "voluri": "node-1,node-2:vol/sub", "options": {"ro": "true"}}]}
+type Export struct {
+	Version int              `json:"version"`
+	Volumes []ExportedVolume `json:"volumes"`
+}
+
+//ExportedVolume definition of a volume, as given to docker volume create
+type ExportedVolume struct {
+	Name      string            `json:"name"`
+	VolumeURI string            `json:"voluri"`
+	Options   map[string]string `json:"options,omitempty"`
+}
+
+//ImportResult action done (or that would be done in dry-run) for an imported volume
+type ImportResult struct {
+	Name   string `json:"name"`
+	Action string `json:"action"` //created, unchanged, skipped, overwritten, renamed or failed
+	As     string `json:"as,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+//Export return the definitions of all volumes sorted by name
+func (d *GlusterDriver) Export() (*Export, error) {
+	d.GetLock().RLock()
+	defer d.GetLock().RUnlock()
+	e := &Export{Version: ExportVersion, Volumes: make([]ExportedVolume, 0, len(d.volumes))}
+	for name, v := range d.volumes {
+		e.Volumes = append(e.Volumes, ExportedVolume{Name: name, VolumeURI: v.VolumeURI, Options: v.Options})
+	}
+	sort.Slice(e.Volumes, func(i, j int) bool { return e.Volumes[i].Name < e.Volumes[j].Name })
+	return e, nil
+}
+
+//Import create the volumes of an export, existing volumes with a different definition are handled following conflict
+func (d *GlusterDriver) Import(e *Export, conflict string, dryRun bool) ([]ImportResult, error) {
+	if e.Version != ExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d (supported: %d)", e.Version, ExportVersion)
+	}
+	switch conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return nil, fmt.Errorf("unknown conflict handling %s (skip, overwrite or rename)", conflict)
+	}
+	results := make([]ImportResult, 0, len(e.Volumes))
+	planned := make(map[string]bool) //Names taken by previous entries of the import
+	for _, ev := range e.Volumes {
+		res := ImportResult{Name: ev.Name}
+		err := d.importVolume(ev, conflict, dryRun, planned, &res)
+		if err != nil {
+			res.Action, res.Error = "failed", err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (d *GlusterDriver) importVolume(ev ExportedVolume, conflict string, dryRun bool, planned map[string]bool, res *ImportResult) error {
+	if ev.Name == "" {
+		return fmt.Errorf("volume without name")
+	}
+	opts := make(map[string]string, len(ev.Options)+1)
+	for k, val := range ev.Options {
+		opts[k] = val
+	}
+	v := &GlusterVolume{VolumeURI: ev.VolumeURI, Options: opts}
+	d.GetLock().RLock()
+	err := v.Validate() //Under lock as defaults, profiles and clusters can be reloaded
+	existing, exist := d.volumes[ev.Name]
+	used := exist && existing.Connections > 0
+	d.GetLock().RUnlock()
+	if err != nil {
+		return err
+	}
+	exist = exist || planned[ev.Name]
+	name := ev.Name
+	var previous *GlusterVolume //Definition to restore if an overwrite fail
+	switch {
+	case exist && existing != nil && existing.VolumeURI == v.VolumeURI && sameOptions(existing.Options, v.Options):
+		res.Action = "unchanged"
+		return nil
+	case !exist:
+		res.Action = "created"
+	case conflict == ConflictSkip:
+		res.Action = "skipped"
+		return nil
+	case conflict == ConflictOverwrite:
+		if used {
+			return fmt.Errorf("volume %s is currently used by a container", name)
+		}
+		res.Action = "overwritten"
+		if !dryRun {
+			if err := d.RemoveVolume(name, false); err != nil {
+				return err
+			}
+			previous = existing
+		}
+	case conflict == ConflictRename:
+		name = d.freeName(ev.Name, planned)
+		res.Action, res.As = "renamed", name
+	}
+	planned[name] = true
+	if dryRun {
+		return nil
+	}
+	opts["voluri"] = escapeVolURI(v.VolumeURI) //Exported expanded
+	err = d.Create(&volume.CreateRequest{Name: name, Options: opts})
+	if err != nil && previous != nil {
+		if rerr := d.restoreVolume(name, previous); rerr != nil {
+			return fmt.Errorf("%v (unable to restore previous definition: %v)", err, rerr)
+		}
+		log.Warnf("Import of %s failed, previous definition restored: %v", name, err)
+	}
+	return err
+}
+
+//restoreVolume create again a volume removed by an overwrite that failed
+func (d *GlusterDriver) restoreVolume(name string, v *GlusterVolume) error {
+	opts := make(map[string]string, len(v.Options)+1)
+	for k, val := range v.Options {
+		opts[k] = val
+	}
+	opts["voluri"] = escapeVolURI(v.VolumeURI) //Already expanded
+	if err := d.Create(&volume.CreateRequest{Name: name, Options: opts}); err != nil {
+		return err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	if r, ok := d.volumes[name]; ok {
+		r.Initialized = v.Initialized
+	}
+	return d.SaveConfig()
+}
+
+//freeName return the first name-N not used
+func (d *GlusterDriver) freeName(name string, planned map[string]bool) string {
+	d.GetLock().RLock()
+	defer d.GetLock().RUnlock()
+	for i := 1; ; i++ {
+		n := fmt.Sprintf("%s-%d", name, i)
+		if _, ok := d.volumes[n]; !ok && !planned[n] {
+			return n
+		}
+	}
+}
+
+func sameOptions(a, b map[string]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
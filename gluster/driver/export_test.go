@@ -0,0 +1,143 @@
+package driver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string) { CfgFolder = cfg }(CfgFolder)
+
+	CfgFolder = filepath.Join(dir, "src")
+	src := Init(filepath.Join(dir, "src-root"), false)
+	for name, voluri := range map[string]string{"a": "node:vol/a", "b": "node:vol/b", "c": "node:vol/c"} {
+		if err := src.Create(&volume.CreateRequest{Name: name, Options: map[string]string{"voluri": voluri, "uid": "1000"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, err := src.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"version":1,"volumes":[{"name":"a","voluri":"node:vol/a","options":{"uid":"1000"}},{"name":"b","voluri":"node:vol/b","options":{"uid":"1000"}},{"name":"c","voluri":"node:vol/c","options":{"uid":"1000"}}]}` {
+		t.Errorf("Unexpected export format %s", b)
+	}
+
+	CfgFolder = filepath.Join(dir, "dst")
+	dst := Init(filepath.Join(dir, "dst-root"), false)
+	for name, voluri := range map[string]string{"a": "node:vol/a", "b": "other:vol"} {
+		if err := dst.Create(&volume.CreateRequest{Name: name, Options: map[string]string{"voluri": voluri, "uid": "1000"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.Volumes = append(e.Volumes, ExportedVolume{Name: "d", VolumeURI: "invalid"})
+
+	tt := []struct {
+		conflict string
+		actions  []string
+	}{
+		{ConflictSkip, []string{"unchanged", "skipped", "created", "failed"}},
+		{ConflictRename, []string{"unchanged", "renamed", "created", "failed"}},
+		{ConflictOverwrite, []string{"unchanged", "overwritten", "created", "failed"}},
+	}
+	for _, test := range tt {
+		results, err := dst.Import(e, test.conflict, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actions []string
+		for _, r := range results {
+			actions = append(actions, r.Action)
+		}
+		if !reflect.DeepEqual(actions, test.actions) {
+			t.Errorf("Expected %v with %s, got %v", test.actions, test.conflict, results)
+		}
+	}
+	if len(dst.volumes) != 2 {
+		t.Errorf("Expected dry-run to not create volumes, got %v", dst.volumes)
+	}
+
+	results, err := dst.Import(e, ConflictRename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[1].As != "b-1" {
+		t.Errorf("Expected b to be renamed b-1, got %v", results[1])
+	}
+	if v, ok := dst.volumes["b-1"]; !ok || v.VolumeURI != "node:vol/b" || dst.volumes["b"].VolumeURI != "other:vol" {
+		t.Errorf("Unexpected volumes after rename %v", dst.volumes)
+	}
+	if _, err := dst.Import(e, "merge", false); err == nil {
+		t.Error("Expected unknown conflict handling to fail")
+	}
+	if _, err := dst.Import(&Export{Version: 2}, ConflictSkip, false); err == nil {
+		t.Error("Expected unknown export version to fail")
+	}
+}
+
+func TestImportOverwriteFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string) { CfgFolder = cfg }(CfgFolder)
+	CfgFolder = filepath.Join(dir, "cfg")
+
+	d := Init(filepath.Join(dir, "root"), true)
+	if err := d.Create(&volume.CreateRequest{Name: "a", Options: map[string]string{"voluri": "node:vol/a", "uid": "1000"}}); err != nil {
+		t.Fatal(err)
+	}
+	d.volumes["a"].Initialized = true
+	//Mountpoint of the new definition is not empty so its Create fail
+	blocked := filepath.Join(dir, "root", getMountName(d, "a", &GlusterVolume{VolumeURI: "other:vol"}))
+	if err := os.MkdirAll(filepath.Join(blocked, "data"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := d.Import(&Export{Version: ExportVersion, Volumes: []ExportedVolume{{Name: "a", VolumeURI: "other:vol"}}}, ConflictOverwrite, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != "failed" {
+		t.Errorf("Expected overwrite to fail, got %v", results[0])
+	}
+	v, ok := d.volumes["a"]
+	if !ok || v.VolumeURI != "node:vol/a" || v.Options["uid"] != "1000" || !v.Initialized {
+		t.Errorf("Expected previous definition to be restored, got %v", v)
+	}
+}
+
+func TestImportLiteralDollar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string) { CfgFolder = cfg }(CfgFolder)
+	CfgFolder = filepath.Join(dir, "cfg")
+
+	d := Init(filepath.Join(dir, "root"), false)
+	voluri := "node:vol/a$${name}"
+	if _, err := d.Import(&Export{Version: ExportVersion, Volumes: []ExportedVolume{{Name: "a", VolumeURI: voluri}}}, ConflictSkip, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := d.volumes["a"]; !ok || v.VolumeURI != voluri {
+		t.Errorf("Expected imported voluri to be kept as is, got %v", v)
+	}
+}
@@ -0,0 +1,65 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//GCResult directories under the root not referenced by any mountpoint
+type GCResult struct {
+	Removed  []string `json:"removed"`
+	NonEmpty []string `json:"non_empty"`
+	Mounted  []string `json:"mounted"`
+}
+
+//GC remove empty directories under the root that are not a known mountpoint, non-empty or mounted ones are only reported
+func (d *GlusterDriver) GC(dryRun bool) (*GCResult, error) {
+	res := &GCResult{Removed: []string{}, NonEmpty: []string{}, Mounted: []string{}}
+	mounts, err := listMounts()
+	if err != nil {
+		return nil, err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	entries, err := ioutil.ReadDir(d.root)
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(d.mounts))
+	for _, m := range d.mounts {
+		known[filepath.Clean(m.Path)] = true
+	}
+	for _, fi := range entries {
+		path := filepath.Join(d.root, fi.Name())
+		if !fi.IsDir() || known[path] {
+			continue
+		}
+		if mounts[path] {
+			log.Warnf("GC: %s is mounted but not used by any volume", path)
+			res.Mounted = append(res.Mounted, path)
+			continue
+		}
+		empty, err := isEmpty(path)
+		if err != nil {
+			return res, err
+		}
+		if !empty {
+			log.Warnf("GC: %s is not used by any volume but is not empty, it need to be checked manually", path)
+			res.NonEmpty = append(res.NonEmpty, path)
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return res, err
+			}
+			log.Infof("GC: removed unused %s", path)
+		}
+		res.Removed = append(res.Removed, path)
+	}
+	return res, nil
+}
@@ -0,0 +1,67 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestGC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-gc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, proc string) { CfgFolder, ProcMounts = cfg, proc }(CfgFolder, ProcMounts)
+	CfgFolder = filepath.Join(dir, "cfg")
+	ProcMounts = filepath.Join(dir, "mounts")
+	root := filepath.Join(dir, "root")
+	if err := ioutil.WriteFile(ProcMounts, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := Init(root, false)
+	if res, err := d.GC(false); err != nil || len(res.Removed) != 0 {
+		t.Errorf("Expected nothing to collect without root, got %v (%v)", res, err)
+	}
+	if err := d.Create(&volume.CreateRequest{Name: "used", Options: map[string]string{"voluri": "node:vol"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"empty", "full/data", "mounted"} {
+		if err := os.MkdirAll(filepath.Join(root, p), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "file"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(ProcMounts, []byte("node:vol "+filepath.Join(root, "mounted")+" fuse.glusterfs rw 0 0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &GCResult{
+		Removed:  []string{filepath.Join(root, "empty")},
+		NonEmpty: []string{filepath.Join(root, "full")},
+		Mounted:  []string{filepath.Join(root, "mounted")},
+	}
+	res, err := d.GC(true)
+	if err != nil || !reflect.DeepEqual(res, expected) {
+		t.Errorf("Expected %v, got %v (%v)", expected, res, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "empty")); err != nil {
+		t.Error("Expected dry-run to not remove anything")
+	}
+	if res, err = d.GC(false); err != nil || !reflect.DeepEqual(res, expected) {
+		t.Errorf("Expected %v, got %v (%v)", expected, res, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "empty")); !os.IsNotExist(err) {
+		t.Error("Expected empty unused directory to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(root, "used")); err != nil {
+		t.Error("Expected mountpoint of volume to be kept")
+	}
+}
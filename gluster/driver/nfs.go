@@ -0,0 +1,159 @@
+package driver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//Protocols ways of accessing a gluster volume, glusterfs volumes are mounted with a backend and nfs ones through a NFS-Ganesha export
+var Protocols = []string{"glusterfs", "nfs"}
+
+var (
+	nfsVersions  = []string{"3", "4", "4.0", "4.1", "4.2"}
+	nfsOptionsRe = regexp.MustCompile(`^[A-Za-z0-9_.=:\-]+(,[A-Za-z0-9_.=:\-]+)*$`)
+	nfsExportRe  = regexp.MustCompile(`^/[A-Za-z0-9_.\-/]*$`)
+)
+
+//validateProtocol check the protocol option and the nfs-* options
+func validateProtocol(opts map[string]string) error {
+	switch opts["protocol"] {
+	case "", "glusterfs":
+		for _, k := range []string{"nfs-version", "nfs-export", "nfs-options"} {
+			if _, ok := opts[k]; ok {
+				return fmt.Errorf("option %s need protocol=nfs", k)
+			}
+		}
+		return nil
+	case "nfs":
+	default:
+		return fmt.Errorf("unknown protocol %s (%s)", opts["protocol"], strings.Join(Protocols, ", "))
+	}
+	if opts["backend"] != "" {
+		return fmt.Errorf("option backend can't be used with protocol=nfs")
+	}
+	if v := opts["nfs-version"]; v != "" {
+		valid := false
+		for _, known := range nfsVersions {
+			valid = valid || v == known
+		}
+		if !valid {
+			return fmt.Errorf("unsupported nfs-version %s (%s)", v, strings.Join(nfsVersions, ", "))
+		}
+	}
+	if e := opts["nfs-export"]; e != "" && !nfsExportRe.MatchString(e) {
+		return fmt.Errorf("nfs-export need to be an absolute path: %s", e)
+	}
+	if o := opts["nfs-options"]; o != "" && !nfsOptionsRe.MatchString(o) {
+		return fmt.Errorf("nfs-options need to be a comma separated list of mount options: %s", o)
+	}
+	return nil
+}
+
+//nfsBackend mount volumes through the NFS export of a NFS-Ganesha server using the gluster FSAL
+type nfsBackend struct{}
+
+func (nfsBackend) Mount(d *GlusterDriver, spec MountSpec) error {
+	if spec.SSL.Enabled() {
+		return fmt.Errorf("ssl can't be used with protocol=nfs")
+	}
+	if t := spec.URI.Transport; t != "" && t != "tcp" {
+		return fmt.Errorf("transport %s can't be used with protocol=nfs", t)
+	}
+	if len(spec.URI.Options) > 0 {
+		return fmt.Errorf("voluri options can't be used with protocol=nfs")
+	}
+	opts := nfsMountOptions(spec)
+	if spec.Mkdir && spec.URI.Subdir != "" {
+		if err := nfsMkdirSubdir(d, spec, opts); err != nil {
+			return fmt.Errorf("unable to create %s on volume %s: %v", spec.URI.Subdir, spec.URI.Volume, err)
+		}
+	}
+	if spec.ReadOnly {
+		opts = append(opts, "ro")
+	}
+	return nfsMount(d, spec.URI, path.Join(nfsExport(spec), spec.URI.Subdir), opts, spec.Path)
+}
+
+func (nfsBackend) Unmount(d *GlusterDriver, path string) (bool, error) {
+	return false, nil //Same umount command as fuse backend
+}
+
+//nfsExport return the pseudo path of the export of the volume
+func nfsExport(spec MountSpec) string {
+	if e := spec.Options["nfs-export"]; e != "" {
+		return e
+	}
+	return "/" + spec.URI.Volume
+}
+
+//nfsMountOptions translate volume options to nfs mount options, client tuning without nfs equivalent is ignored
+func nfsMountOptions(spec MountSpec) []string {
+	var opts []string
+	if v := spec.Options["nfs-version"]; v != "" {
+		opts = append(opts, "vers="+v)
+	}
+	if t := spec.Options["attribute-timeout"]; t != "" {
+		opts = append(opts, "actimeo="+t)
+	}
+	if spec.Options["negative-timeout"] == "0" {
+		opts = append(opts, "lookupcache=positive")
+	}
+	for _, k := range []string{"entry-timeout", "direct-io-mode", "read-ahead-page-count", "io-threads"} {
+		if _, ok := spec.Options[k]; ok {
+			log.Debugf("Option %s has no nfs equivalent, ignoring it", k)
+		}
+	}
+	if o := spec.Options["nfs-options"]; o != "" {
+		opts = append(opts, strings.Split(o, ",")...)
+	}
+	return opts
+}
+
+//nfsMount mount export on path trying each server of voluri in order (ports of voluri are the ones of glusterd and are not used)
+func nfsMount(d *GlusterDriver, u *VolURI, export string, opts []string, path string) error {
+	optArg := ""
+	if len(opts) > 0 {
+		optArg = " -o " + shellQuote(strings.Join(opts, ","))
+	}
+	var errs []string
+	for _, s := range u.Servers {
+		if s.IsSocket() {
+			return fmt.Errorf("unix socket %s can't be used with protocol=nfs", s.Host)
+		}
+		host := s.Host
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		err := d.RunCmd(fmt.Sprintf("/usr/bin/mount -t nfs%s %s %s", optArg, shellQuote(host+":"+export), path))
+		if err == nil {
+			return nil
+		}
+		log.Warnf("Unable to mount %s from %s: %v", export, s.Host, err)
+		errs = append(errs, fmt.Sprintf("%s: %v", s.Host, err))
+	}
+	return fmt.Errorf("unable to mount %s: %s", export, strings.Join(errs, "; "))
+}
+
+//nfsMkdirSubdir create the subdir on the export by mounting temporarly the root of the export
+func nfsMkdirSubdir(d *GlusterDriver, spec MountSpec, opts []string) error {
+	tmp, err := ioutil.TempDir("", "docker-volume-gluster")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := nfsMount(d, spec.URI, nfsExport(spec), opts, tmp); err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Join(tmp, spec.URI.Subdir), 0755)
+	if uerr := d.UnmountPath(tmp); uerr != nil {
+		log.Warnf("Unable to unmount %s: %v", tmp, uerr)
+	}
+	return err
+}
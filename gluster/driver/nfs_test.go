@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//captureCommands replace runCommand to record commands, commands containing a key of fail are failing
+func captureCommands(fail map[string]bool) (*[]string, func()) {
+	var cmds []string
+	old := runCommand
+	runCommand = func(ctx context.Context, cmd string) ([]byte, error) {
+		cmds = append(cmds, cmd)
+		for s := range fail {
+			if strings.Contains(cmd, s) {
+				return nil, fmt.Errorf("exit status 32")
+			}
+		}
+		return nil, nil
+	}
+	return &cmds, func() { runCommand = old }
+}
+
+func TestValidateProtocol(t *testing.T) {
+	for _, opts := range []map[string]string{
+		{},
+		{"protocol": "glusterfs"},
+		{"protocol": "nfs"},
+		{"protocol": "nfs", "nfs-version": "4.1", "nfs-export": "/gv0", "nfs-options": "hard,timeo=600,proto=tcp"},
+	} {
+		if err := ValidateOptions(opts); err != nil {
+			t.Errorf("Expected %v to be valid, got %v", opts, err)
+		}
+	}
+	for _, opts := range []map[string]string{
+		{"protocol": "smb"},
+		{"nfs-version": "4"},
+		{"protocol": "nfs", "backend": "fuse"},
+		{"protocol": "nfs", "nfs-version": "5"},
+		{"protocol": "nfs", "nfs-export": "gv0"},
+		{"protocol": "nfs", "nfs-options": "hard;reboot"},
+		{"protocol": "nfs", "nfs-options": "hard,"},
+	} {
+		if err := ValidateOptions(opts); err == nil {
+			t.Errorf("Expected %v to be refused", opts)
+		}
+	}
+}
+
+func TestMountCommands(t *testing.T) {
+	cmds, restore := captureCommands(map[string]bool{"node1:": true})
+	defer restore()
+	d := &GlusterDriver{}
+	for _, test := range []struct {
+		uri      string
+		opts     map[string]string
+		expected []string
+	}{
+		{"node1:vol/sub", map[string]string{"ro": "true"},
+			[]string{"glusterfs --volfile-id='vol' -s 'node1' --subdir-mount='/sub' --read-only /mnt/a"}},
+		{"node1,node2:vol/sub", map[string]string{"protocol": "nfs", "ro": "true", "attribute-timeout": "5", "entry-timeout": "5"}, []string{
+			"/usr/bin/mount -t nfs -o 'actimeo=5,ro' 'node1:/vol/sub' /mnt/a",
+			"/usr/bin/mount -t nfs -o 'actimeo=5,ro' 'node2:/vol/sub' /mnt/a",
+		}},
+		{"[fe80::1]:24007:vol", map[string]string{"protocol": "nfs", "nfs-version": "4.1", "nfs-export": "/exports/vol", "nfs-options": "hard,timeo=600", "negative-timeout": "0"},
+			[]string{"/usr/bin/mount -t nfs -o 'vers=4.1,lookupcache=positive,hard,timeo=600' '[fe80::1]:/exports/vol' /mnt/a"}},
+	} {
+		*cmds = nil
+		v := &GlusterVolume{VolumeURI: test.uri, Options: test.opts}
+		if err := d.mountVolume(v, "/mnt/a"); err != nil {
+			t.Errorf("Expected %s to be mounted, got %v", test.uri, err)
+		}
+		if strings.Join(*cmds, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("Expected %s to run %q, got %q", test.uri, test.expected, *cmds)
+		}
+	}
+
+	for _, uri := range []string{"node1:vol", "/run/glusterd.socket:vol", "node2:vol?log-level=DEBUG", "node2:vol?transport=rdma"} {
+		v := &GlusterVolume{VolumeURI: uri, Options: map[string]string{"protocol": "nfs"}}
+		if err := d.mountVolume(v, "/mnt/a"); err == nil {
+			t.Errorf("Expected nfs mount of %s to fail", uri)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package driver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/sapk/docker-volume-gluster/common"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//mountIDRe valid docker mount id, used as name of the subdir of the container
+var mountIDRe = regexp.MustCompile(`^[A-Za-z0-9_\-][A-Za-z0-9_.\-]*$`)
+
+//perContainer return true if each docker mount of the volume get its own subdir
+func perContainer(opts map[string]string) bool {
+	b, _ := parseBoolOpt(opts, "per-container")
+	return b
+}
+
+//isPerContainer return true if the volume use the per-container option
+func (d *GlusterDriver) isPerContainer(name string) bool {
+	d.GetLock().RLock()
+	defer d.GetLock().RUnlock()
+	v, ok := d.volumes[name]
+	return ok && perContainer(v.withDefaults().Options)
+}
+
+//validatePerContainer check the per-container and per-container-cleanup options
+func validatePerContainer(opts map[string]string) error {
+	if _, err := parseBoolOpt(opts, "per-container"); err != nil {
+		return err
+	}
+	switch opts["per-container-cleanup"] {
+	case "", "keep", "delete":
+		return nil
+	}
+	return fmt.Errorf("option per-container-cleanup need to be keep or delete: %s", opts["per-container-cleanup"])
+}
+
+//hasContainer return true if the mount id has a subdir in use
+func (v *GlusterVolume) hasContainer(id string) bool {
+	for _, c := range v.Containers {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
+//removeContainer forget the mount id
+func (v *GlusterVolume) removeContainer(id string) {
+	for i, c := range v.Containers {
+		if c == id {
+			v.Containers = append(v.Containers[:i], v.Containers[i+1:]...)
+			return
+		}
+	}
+}
+
+//mountContainer mount the volume if needed and create the subdir of the docker mount id
+func (d *GlusterDriver) mountContainer(name, id string) (string, error) {
+	if !mountIDRe.MatchString(id) {
+		return "", fmt.Errorf("invalid mount id %q for per-container volume %s", id, name)
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	v, ok := d.volumes[name]
+	if !ok {
+		return "", fmt.Errorf("volume %s not found", name)
+	}
+	m, ok := d.mounts[v.Mount]
+	if !ok {
+		return "", fmt.Errorf("mount %s not found", v.Mount)
+	}
+	path := filepath.Join(m.Path, id)
+	if v.hasContainer(id) {
+		return path, nil
+	}
+	if m.Connections == 0 {
+		if err := d.mountVolume(v, m.Path); err != nil {
+			return "", err
+		}
+	}
+	if err := d.mkdirContainer(v, path); err != nil {
+		if m.Connections == 0 {
+			if uerr := d.UnmountPath(m.Path); uerr != nil {
+				log.Warnf("Unable to unmount %s: %v", m.Path, uerr)
+			}
+		}
+		return "", fmt.Errorf("unable to create %s for volume %s: %v", id, name, err)
+	}
+	v.Containers = append(v.Containers, id)
+	common.AddN(1, v, m)
+	return path, d.SaveConfig()
+}
+
+//mkdirContainer create the subdir of a container if needed with ownership and permissions of the volume root options
+func (d *GlusterDriver) mkdirContainer(v *GlusterVolume, path string) error {
+	root, err := parseRootOptions(v.withDefaults().Options)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil //Root options are only applied on new subdirs
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	return root.apply(path)
+}
+
+//unmountContainer release the subdir of the docker mount id (deleted with per-container-cleanup=delete) and unmount the volume when it was the last one
+func (d *GlusterDriver) unmountContainer(name, id string) error {
+	log.Debugf("Entering unmountContainer: name: %s, id: %s", name, id)
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	v, ok := d.volumes[name]
+	if !ok {
+		return fmt.Errorf("volume %s not found", name)
+	}
+	m, ok := d.mounts[v.Mount]
+	if !ok {
+		return fmt.Errorf("mount %s not found", v.Mount)
+	}
+	if !v.hasContainer(id) { //Already released or never mounted, counts belong to other containers
+		log.Warnf("Mount %s of volume %s is not known, ignoring unmount", id, name)
+		return nil
+	}
+	if v.withDefaults().Options["per-container-cleanup"] == "delete" {
+		log.Debugf("Deleting %s of volume %s", id, name)
+		if err := os.RemoveAll(filepath.Join(m.Path, id)); err != nil {
+			log.Warnf("Unable to delete %s of volume %s: %v", id, name, err)
+		}
+	}
+	if m.Connections <= 1 {
+		if err := d.UnmountPath(m.Path); err != nil {
+			return err
+		}
+		common.SetN(0, m, v)
+		v.Containers = nil
+		d.releaseCredentials(v)
+	} else {
+		common.AddN(-1, m, v)
+		v.removeContainer(id)
+	}
+	return d.SaveConfig()
+}
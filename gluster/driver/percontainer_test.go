@@ -0,0 +1,87 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestPerContainer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-percontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string) { CfgFolder = cfg }(CfgFolder)
+	CfgFolder = filepath.Join(dir, "cfg")
+	cmds, restore := captureCommands(nil)
+	defer restore()
+
+	if err := ValidateOptions(map[string]string{"per-container": "true", "per-container-cleanup": "archive"}); err == nil {
+		t.Error("Expected unknown per-container-cleanup to be refused")
+	}
+
+	d := Init(filepath.Join(dir, "root"), true)
+	for name, cleanup := range map[string]string{"keep": "keep", "delete": "delete"} {
+		if err := d.Create(&volume.CreateRequest{Name: name, Options: map[string]string{"voluri": "node:vol", "per-container": "true", "per-container-cleanup": cleanup}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"keep", "delete"} {
+		*cmds = nil
+		root := d.mounts[d.volumes[name].Mount].Path
+		paths := map[string]string{}
+		for _, id := range []string{"c1", "c2", "c1"} {
+			r, err := d.Mount(&volume.MountRequest{Name: name, ID: id})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Mountpoint != filepath.Join(root, id) {
+				t.Errorf("Expected %s to be mounted on its own subdir, got %s", id, r.Mountpoint)
+			}
+			if _, err := os.Stat(r.Mountpoint); err != nil {
+				t.Errorf("Expected subdir of %s to exist: %v", id, err)
+			}
+			paths[id] = r.Mountpoint
+		}
+		if len(*cmds) != 1 || !strings.HasPrefix((*cmds)[0], "glusterfs ") {
+			t.Errorf("Expected volume to be mounted once, got %q", *cmds)
+		}
+		if v := d.volumes[name]; v.Connections != 2 || len(v.Containers) != 2 {
+			t.Errorf("Expected 2 containers, got %d (%v)", v.Connections, v.Containers)
+		}
+		if _, err := d.Mount(&volume.MountRequest{Name: name, ID: "../c1"}); err == nil {
+			t.Error("Expected mount id escaping the volume to be refused")
+		}
+
+		if err := d.Unmount(&volume.UnmountRequest{Name: name, ID: "c1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Unmount(&volume.UnmountRequest{Name: name, ID: "c1"}); err != nil {
+			t.Fatal(err)
+		}
+		if v := d.volumes[name]; v.Connections != 1 || len(v.Containers) != 1 {
+			t.Errorf("Expected unknown mount id to not change counts, got %d (%v)", v.Connections, v.Containers)
+		}
+		if _, err := os.Stat(paths["c1"]); (err == nil) != (name == "keep") {
+			t.Errorf("Expected subdir existence after unmount with %s cleanup to be %v, got %v", name, name == "keep", err)
+		}
+		if len(*cmds) != 1 {
+			t.Errorf("Expected volume to stay mounted for c2, got %q", *cmds)
+		}
+		if err := d.Unmount(&volume.UnmountRequest{Name: name, ID: "c2"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(*cmds) != 2 || !strings.HasPrefix((*cmds)[1], "/usr/bin/umount ") {
+			t.Errorf("Expected volume to be unmounted after last container, got %q", *cmds)
+		}
+		if v := d.volumes[name]; v.Connections != 0 || len(v.Containers) != 0 {
+			t.Errorf("Expected no containers, got %d (%v)", v.Connections, v.Containers)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package driver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Profiles named sets of client options defined in daemon config, overriding builtin ones of same name
+var Profiles = map[string]map[string]string{}
+
+//builtinProfiles client tuning presets selectable with profile=name
+var builtinProfiles = map[string]map[string]string{
+	//Many small files read often (web assets, source code): cache metadata and lookups
+	"smallfile": {
+		"attribute-timeout": "30",
+		"entry-timeout":     "30",
+		"negative-timeout":  "10",
+		"direct-io-mode":    "disable",
+		"io-threads":        "16",
+	},
+	//Large files read sequentially (media, backups): read ahead as much as possible
+	"largefile": {
+		"direct-io-mode":        "disable",
+		"read-ahead-page-count": "16",
+		"io-threads":            "8",
+	},
+	//Files written concurrently (databases): no client cache
+	"database": {
+		"attribute-timeout":     "0",
+		"entry-timeout":         "0",
+		"negative-timeout":      "0",
+		"direct-io-mode":        "enable",
+		"read-ahead-page-count": "1",
+	},
+}
+
+//clientOption tuning option of the glusterfs client usable in volume options and profiles
+type clientOption struct {
+	name   string
+	arg    string
+	verify func(val string) error
+}
+
+//clientOptions in the order they are given to glusterfs
+var clientOptions = []clientOption{
+	{"attribute-timeout", "--attribute-timeout=%s", intRange(0, 3600)},
+	{"entry-timeout", "--entry-timeout=%s", intRange(0, 3600)},
+	{"negative-timeout", "--negative-timeout=%s", intRange(0, 3600)},
+	{"direct-io-mode", "--direct-io-mode=%s", oneOf("enable", "disable")},
+	{"read-ahead-page-count", "--xlator-option='*-read-ahead.page-count=%s'", intRange(1, 16)},
+	{"io-threads", "--xlator-option='*-io-threads.thread-count=%s'", intRange(1, 64)}, //Only used if client-io-threads is enabled on the volume
+}
+
+func intRange(min, max int) func(string) error {
+	return func(val string) error {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("need to be an integer between %d and %d", min, max)
+		}
+		return nil
+	}
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(val string) error {
+		for _, v := range values {
+			if val == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("need to be one of %s", strings.Join(values, ", "))
+	}
+}
+
+//validateClientOptions check the value of client tuning options present in opts
+func validateClientOptions(opts map[string]string) error {
+	for _, o := range clientOptions {
+		if val, ok := opts[o.name]; ok {
+			if err := o.verify(val); err != nil {
+				return fmt.Errorf("option %s %v: %s", o.name, err, val)
+			}
+		}
+	}
+	return nil
+}
+
+//clientArgs return the glusterfs arguments of the client tuning options present in opts
+func clientArgs(opts map[string]string) string {
+	args := ""
+	for _, o := range clientOptions {
+		if val := opts[o.name]; val != "" {
+			args += " " + fmt.Sprintf(o.arg, val)
+		}
+	}
+	return args
+}
+
+//ValidateProfile check that a profile only contains valid client tuning options
+func ValidateProfile(p map[string]string) error {
+	for k := range p {
+		if !isClientOption(k) {
+			return fmt.Errorf("unknown client option %s", k)
+		}
+	}
+	return validateClientOptions(p)
+}
+
+//IsBuiltinProfile return true if name is a profile provided by the driver
+func IsBuiltinProfile(name string) bool {
+	_, ok := builtinProfiles[name]
+	return ok
+}
+
+//ProfileNames return the names of builtin and configured profiles sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(builtinProfiles)+len(Profiles))
+	for name := range builtinProfiles {
+		if _, ok := Profiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//lookupProfile return the options of a configured or builtin profile
+func lookupProfile(name string) (map[string]string, error) {
+	if p, ok := Profiles[name]; ok {
+		return p, nil
+	}
+	if p, ok := builtinProfiles[name]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown profile %s (available: %s)", name, strings.Join(ProfileNames(), ", "))
+}
+
+func isClientOption(name string) bool {
+	for _, o := range clientOptions {
+		if o.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+//tuning return the client tuning options set in opts
+func tuning(opts map[string]string) map[string]string {
+	t := make(map[string]string)
+	for _, o := range clientOptions {
+		if val, ok := opts[o.name]; ok {
+			t[o.name] = val
+		}
+	}
+	return t
+}
@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestProfiles(t *testing.T) {
+	defer func(p map[string]map[string]string, opts map[string]string) { Profiles, DefaultOptions = p, opts }(Profiles, DefaultOptions)
+	Profiles = map[string]map[string]string{
+		"media":    {"read-ahead-page-count": "8"},
+		"database": {"direct-io-mode": "disable"},
+	}
+
+	v := &GlusterVolume{VolumeURI: "node:vol", Options: map[string]string{"profile": "smallfile", "entry-timeout": "5"}}
+	c := v.withDefaults()
+	if c.Options["entry-timeout"] != "5" || c.Options["attribute-timeout"] != "30" {
+		t.Errorf("Expected volume options to override profile, got %v", c.Options)
+	}
+	expected := " --attribute-timeout=30 --entry-timeout=5 --negative-timeout=10 --direct-io-mode=disable --xlator-option='*-io-threads.thread-count=16'"
+	if args := clientArgs(c.Options); args != expected {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+	if c := (&GlusterVolume{Options: map[string]string{"profile": "database"}}).withDefaults(); !reflect.DeepEqual(tuning(c.Options), map[string]string{"direct-io-mode": "disable"}) {
+		t.Errorf("Expected configured profile to override builtin one, got %v", c.Options)
+	}
+	DefaultOptions = map[string]string{"profile": "media"}
+	if c := (&GlusterVolume{}).withDefaults(); c.Options["read-ahead-page-count"] != "8" {
+		t.Errorf("Expected default profile to be applied, got %v", c.Options)
+	}
+
+	s := v.GetStatus()
+	if s["profile"] != "smallfile" || s["tuning"].(map[string]string)["attribute-timeout"] != "30" {
+		t.Errorf("Expected profile and tuning in status, got %v", s)
+	}
+	if names := ProfileNames(); !reflect.DeepEqual(names, []string{"database", "largefile", "media", "smallfile"}) {
+		t.Errorf("Unexpected profile names %v", names)
+	}
+
+	tt := map[string]map[string]string{
+		"attribute-timeout": {"attribute-timeout": "-1"},
+		"direct-io-mode":    {"direct-io-mode": "on"},
+		"io-threads":        {"io-threads": "0"},
+		"unknown":           {"uid": "1000"},
+	}
+	for expected, p := range tt {
+		if err := ValidateProfile(p); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error about %s for %v, got %v", expected, p, err)
+		}
+	}
+}
+
+func TestCreateWithProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string) { CfgFolder = cfg }(CfgFolder)
+	CfgFolder = filepath.Join(dir, "cfg")
+
+	d := Init(filepath.Join(dir, "root"), false)
+	if err := d.Create(&volume.CreateRequest{Name: "web", Options: map[string]string{"voluri": "node:vol", "profile": "smallfile"}}); err != nil {
+		t.Error(err)
+	}
+	if err := d.Create(&volume.CreateRequest{Name: "typo", Options: map[string]string{"voluri": "node:vol", "profile": "smalfile"}}); err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Errorf("Expected unknown profile to be refused, got %v", err)
+	}
+	if err := d.Create(&volume.CreateRequest{Name: "bad", Options: map[string]string{"voluri": "node:vol", "entry-timeout": "soon"}}); err == nil {
+		t.Error("Expected invalid tuning option to be refused")
+	}
+}
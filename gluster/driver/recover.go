@@ -0,0 +1,89 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//DockerSocket unix socket of the docker engine API
+var DockerSocket = "/var/run/docker.sock"
+
+//dockerVolume volume as returned by the docker engine API
+type dockerVolume struct {
+	Name    string            `json:"Name"`
+	Driver  string            `json:"Driver"`
+	Options map[string]string `json:"Options"`
+}
+
+//adoptMountpoint accept an existing mountpoint found in recovery mode, if still mounted it is considered used
+func adoptMountpoint(m *GlusterMountpoint) error {
+	mounted, err := isMounted(m.Path)
+	if err != nil {
+		return err
+	}
+	if mounted {
+		log.Warnf("Recovery: adopting %s still mounted, it is considered used by a container", m.Path)
+		m.Connections = 1
+	} else {
+		log.Warnf("Recovery: adopting %s that already exist and is not empty", m.Path)
+	}
+	return nil
+}
+
+//DockerVolumes return the definitions of the volumes known by docker using one of the drivers
+func DockerVolumes(drivers []string) (*Export, error) {
+	c := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", DockerSocket)
+			},
+		},
+		Timeout: 30 * time.Second,
+	}
+	resp, err := c.Get("http://docker/volumes")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list docker volumes: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to list docker volumes: %s", resp.Status)
+	}
+	var list struct {
+		Volumes []dockerVolume `json:"Volumes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("unable to decode docker volumes: %v", err)
+	}
+
+	known := make(map[string]bool, len(drivers))
+	for _, name := range drivers {
+		known[name] = true
+	}
+	e := &Export{Version: ExportVersion, Volumes: []ExportedVolume{}}
+	for _, dv := range list.Volumes {
+		if !known[dv.Driver] {
+			continue
+		}
+		if dv.Options["voluri"] == "" {
+			log.Warnf("Recovery: docker volume %s has no voluri option, ignoring it", dv.Name)
+			continue
+		}
+		opts := make(map[string]string, len(dv.Options))
+		for k, val := range dv.Options {
+			if k != "voluri" {
+				opts[k] = val
+			}
+		}
+		e.Volumes = append(e.Volumes, ExportedVolume{Name: dv.Name, VolumeURI: dv.Options["voluri"], Options: opts})
+	}
+	sort.Slice(e.Volumes, func(i, j int) bool { return e.Volumes[i].Name < e.Volumes[j].Name })
+	return e, nil
+}
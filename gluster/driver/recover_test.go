@@ -0,0 +1,90 @@
+package driver
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestDockerVolumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-recover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(s string) { DockerSocket = s }(DockerSocket)
+	DockerSocket = filepath.Join(dir, "docker.sock")
+
+	l, err := net.Listen("unix", DockerSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/volumes" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"Volumes":[
+			{"Name":"b","Driver":"sapk/plugin-gluster:latest","Options":{"voluri":"node:vol/b","ro":"true"}},
+			{"Name":"a","Driver":"gluster","Options":{"voluri":"node:vol/a"}},
+			{"Name":"local","Driver":"local","Options":null},
+			{"Name":"broken","Driver":"gluster","Options":{}}
+		],"Warnings":null}`))
+	}))
+
+	e, err := DockerVolumes([]string{"gluster", "sapk/plugin-gluster:latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Volumes) != 2 || e.Volumes[0].Name != "a" || e.Volumes[1].VolumeURI != "node:vol/b" || e.Volumes[1].Options["ro"] != "true" {
+		t.Errorf("Unexpected recovered volumes %v", e.Volumes)
+	}
+	if _, ok := e.Volumes[1].Options["voluri"]; ok {
+		t.Error("Expected voluri to not be kept in options")
+	}
+}
+
+func TestRecoveryMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-recover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, proc string, mode bool) { CfgFolder, ProcMounts, RecoveryMode = cfg, proc, mode }(CfgFolder, ProcMounts, RecoveryMode)
+	CfgFolder = filepath.Join(dir, "cfg")
+	ProcMounts = filepath.Join(dir, "mounts")
+	root := filepath.Join(dir, "root")
+	for _, name := range []string{"mounted", "leftover"} { //Mountpoints left by a previous run
+		if err := os.MkdirAll(filepath.Join(root, name, "data"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(ProcMounts, []byte("node:vol "+filepath.Join(root, "mounted")+" fuse.glusterfs rw 0 0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := Init(root, false)
+	req := &volume.CreateRequest{Name: "mounted", Options: map[string]string{"voluri": "node:vol"}}
+	if err := d.Create(req); err == nil {
+		t.Error("Expected Create on existing mountpoint to fail without recovery mode")
+	}
+	RecoveryMode = true
+	if err := d.Create(req); err != nil {
+		t.Fatal(err)
+	}
+	if d.volumes["mounted"].Connections != 1 || d.mounts["mounted"].Connections != 1 {
+		t.Error("Expected adopted mounted volume to be considered used")
+	}
+	if err := d.Create(&volume.CreateRequest{Name: "leftover", Options: map[string]string{"voluri": "node:vol/leftover"}}); err != nil {
+		t.Fatal(err)
+	}
+	if d.volumes["leftover"].Connections != 0 {
+		t.Error("Expected adopted unmounted volume to not be used")
+	}
+}
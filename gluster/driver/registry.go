@@ -0,0 +1,200 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const registryExt = ".json"
+
+var (
+	//RegistryVolURI voluri of the gluster volume used to share volume definitions between hosts (disabled if empty)
+	RegistryVolURI = ""
+	//RegistryDir folder where volume definitions are shared, RegistryVolURI is mounted on it if set
+	RegistryDir = ""
+)
+
+//Registry shared store of volume definitions
+type Registry interface {
+	List() (map[string]*GlusterVolume, error)
+	Get(name string) (*GlusterVolume, error)
+	Put(name string, v *GlusterVolume) error
+	Delete(name string) error
+}
+
+//registryRecord definition of a volume as stored in the registry
+type registryRecord struct {
+	VolumeURI string            `json:"voluri"`
+	Options   map[string]string `json:"options,omitempty"`
+}
+
+//dirRegistry registry storing each volume as a json file in a folder
+type dirRegistry struct {
+	path string
+}
+
+//NewDirRegistry return a registry storing volume definitions in path
+func NewDirRegistry(path string) (Registry, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	return &dirRegistry{path: path}, nil
+}
+
+//lock take a lock on the whole registry, exclusive or shared, and return the unlock function
+func (r *dirRegistry) lock(exclusive bool) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(r.path, ".lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to lock registry: %v", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (r *dirRegistry) file(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+		return "", fmt.Errorf("invalid volume name %s", name)
+	}
+	return filepath.Join(r.path, name+registryExt), nil
+}
+
+func (r *dirRegistry) read(file string) (*GlusterVolume, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rec registryRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %v", file, err)
+	}
+	return &GlusterVolume{VolumeURI: rec.VolumeURI, Options: rec.Options}, nil
+}
+
+//List return all volume definitions of the registry
+func (r *dirRegistry) List() (map[string]*GlusterVolume, error) {
+	unlock, err := r.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	files, err := filepath.Glob(filepath.Join(r.path, "*"+registryExt))
+	if err != nil {
+		return nil, err
+	}
+	vols := make(map[string]*GlusterVolume, len(files))
+	for _, f := range files {
+		v, err := r.read(f)
+		if err != nil {
+			log.Warnf("Ignoring registry entry: %v", err)
+			continue
+		}
+		vols[strings.TrimSuffix(filepath.Base(f), registryExt)] = v
+	}
+	return vols, nil
+}
+
+//Get return the definition of a volume or nil if not found
+func (r *dirRegistry) Get(name string) (*GlusterVolume, error) {
+	file, err := r.file(name)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := r.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	v, err := r.read(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return v, err
+}
+
+//Put store the definition of a volume
+func (r *dirRegistry) Put(name string, v *GlusterVolume) error {
+	file, err := r.file(name)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(registryRecord{VolumeURI: v.VolumeURI, Options: v.Options})
+	if err != nil {
+		return err
+	}
+	unlock, err := r.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+//Delete remove the definition of a volume
+func (r *dirRegistry) Delete(name string) error {
+	file, err := r.file(name)
+	if err != nil {
+		return err
+	}
+	unlock, err := r.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//openRegistry mount the registry volume if needed and open the shared registry
+func (d *GlusterDriver) openRegistry() (Registry, error) {
+	path := RegistryDir
+	if path == "" {
+		path = filepath.Join(CfgFolder, "registry")
+	}
+	if RegistryVolURI != "" {
+		if err := d.mountRegistry(path); err != nil {
+			return nil, fmt.Errorf("unable to mount registry %s: %v", RegistryVolURI, err)
+		}
+	}
+	return NewDirRegistry(path)
+}
+
+func (d *GlusterDriver) mountRegistry(path string) error {
+	if mounted, err := isMounted(path); err != nil || mounted {
+		return err
+	}
+	u, ssl, err := d.resolve(&GlusterVolume{VolumeURI: RegistryVolURI})
+	if err != nil {
+		return err
+	}
+	args, err := u.Args()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return err
+	}
+	return d.RunCmd(fmt.Sprintf("glusterfs %s%s %s", args, ssl.cmdArgs(), path))
+}
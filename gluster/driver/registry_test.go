@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestDirRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := NewDirRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.Get("test"); err != nil || v != nil {
+		t.Error("Expected unknown volume to be nil, got ", v, err)
+	}
+	if err := r.Put("test", &GlusterVolume{VolumeURI: "node:volume", Options: map[string]string{"ro": "true"}, Connections: 3}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := r.Get("test")
+	if err != nil || v == nil || v.VolumeURI != "node:volume" || v.Options["ro"] != "true" || v.Connections != 0 {
+		t.Error("Expected stored definition, got ", v, err)
+	}
+	vols, err := r.List()
+	if err != nil || len(vols) != 1 || vols["test"] == nil {
+		t.Error("Expected one volume, got ", vols, err)
+	}
+	if err := r.Put("../escape", &GlusterVolume{VolumeURI: "node:volume"}); err == nil {
+		t.Error("Expected invalid name to be rejected")
+	}
+	if err := r.Delete("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete("test"); err != nil {
+		t.Error("Expected deleting unknown volume to succeed, got ", err)
+	}
+	if vols, err := r.List(); err != nil || len(vols) != 0 {
+		t.Error("Expected no volume, got ", vols, err)
+	}
+}
+
+func TestSharedRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, reg string) { CfgFolder, RegistryDir = cfg, reg }(CfgFolder, RegistryDir)
+	RegistryDir = filepath.Join(dir, "registry")
+
+	CfgFolder = filepath.Join(dir, "host-1")
+	d1 := Init(filepath.Join(dir, "root-1"), false)
+	CfgFolder = filepath.Join(dir, "host-2")
+	d2 := Init(filepath.Join(dir, "root-2"), false)
+
+	CfgFolder = filepath.Join(dir, "host-1")
+	if err := d1.Create(&volume.CreateRequest{Name: "shared", Options: map[string]string{"voluri": "node:volume"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	CfgFolder = filepath.Join(dir, "host-2")
+	l, err := d2.List()
+	if err != nil || len(l.Volumes) != 1 || l.Volumes[0].Name != "shared" {
+		t.Error("Expected shared volume to be listed on other host, got ", l, err)
+	}
+	r, err := d2.Path(&volume.PathRequest{Name: "shared"})
+	if err != nil || r.Mountpoint != filepath.Join(dir, "root-2", "shared") {
+		t.Error("Expected shared volume to be created on other host, got ", r, err)
+	}
+
+	if err := d2.Remove(&volume.RemoveRequest{Name: "shared"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d1.registry.Get("shared"); err != nil || v != nil {
+		t.Error("Expected removed volume to be deleted from registry, got ", v, err)
+	}
+}
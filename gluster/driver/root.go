@@ -0,0 +1,116 @@
+package driver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//rootOptions ownership and permissions applied on the root of the volume (or subdir) once mounted
+type rootOptions struct {
+	UID   int
+	GID   int
+	Mode  os.FileMode
+	Mkdir bool
+}
+
+//parseRootOptions extract uid, gid, mode and mkdir options (UID and GID are -1 and Mode 0 if not set)
+func parseRootOptions(opts map[string]string) (rootOptions, error) {
+	r := rootOptions{UID: -1, GID: -1}
+	var err error
+	if r.UID, err = parseIDOpt(opts, "uid"); err != nil {
+		return r, err
+	}
+	if r.GID, err = parseIDOpt(opts, "gid"); err != nil {
+		return r, err
+	}
+	if val := opts["mode"]; val != "" {
+		mode, err := strconv.ParseUint(val, 8, 32)
+		if err != nil || mode > 07777 {
+			return r, fmt.Errorf("option mode is not a valid octal permission: %s", val)
+		}
+		r.Mode = fileMode(mode)
+	}
+	if r.Mkdir, err = parseBoolOpt(opts, "mkdir"); err != nil {
+		return r, err
+	}
+	ro, err := parseBoolOpt(opts, "ro")
+	if err != nil {
+		return r, err
+	}
+	if ro && (r.UID != -1 || r.GID != -1 || r.Mode != 0) { //Would fail with EROFS at first mount
+		return r, fmt.Errorf("options uid, gid and mode can't be applied on a read-only volume")
+	}
+	return r, nil
+}
+
+//fileMode convert unix permission bits to os.FileMode (setuid, setgid and sticky bits are not the same in both)
+func fileMode(mode uint64) os.FileMode {
+	m := os.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
+
+func parseIDOpt(opts map[string]string, key string) (int, error) {
+	val := opts[key]
+	if val == "" {
+		return -1, nil
+	}
+	id, err := strconv.Atoi(val)
+	if err != nil || id < 0 {
+		return -1, fmt.Errorf("option %s is not a valid id: %s", key, val)
+	}
+	return id, nil
+}
+
+//apply set ownership and permissions on path
+func (r rootOptions) apply(path string) error {
+	if r.UID != -1 || r.GID != -1 {
+		log.Debugf("Changing owner of %s to %d:%d", path, r.UID, r.GID)
+		if err := os.Chown(path, r.UID, r.GID); err != nil {
+			return err
+		}
+	}
+	if r.Mode != 0 {
+		log.Debugf("Changing mode of %s to %o", path, r.Mode)
+		if err := os.Chmod(path, r.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//mkdirSubdir create the subdir on the remote volume by mounting temporarly the root of the volume
+func (d *GlusterDriver) mkdirSubdir(u *VolURI, extraArgs string) error {
+	root := *u
+	root.Subdir = ""
+	args, err := root.Args()
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir("", "docker-volume-gluster")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := d.RunCmd(fmt.Sprintf("glusterfs %s%s %s", args, extraArgs, tmp)); err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Join(tmp, u.Subdir), 0755)
+	if uerr := d.UnmountPath(tmp); uerr != nil {
+		log.Warnf("Unable to unmount %s: %v", tmp, uerr)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestParseRootOptions(t *testing.T) {
+	tt := []struct {
+		opts   map[string]string
+		result rootOptions
+		err    bool
+	}{
+		{map[string]string{}, rootOptions{UID: -1, GID: -1}, false},
+		{map[string]string{"uid": "1000", "gid": "100", "mode": "0775", "mkdir": "true"}, rootOptions{UID: 1000, GID: 100, Mode: 0775, Mkdir: true}, false},
+		{map[string]string{"mode": "2770"}, rootOptions{UID: -1, GID: -1, Mode: os.ModeSetgid | 0770}, false},
+		{map[string]string{"mode": "5755"}, rootOptions{UID: -1, GID: -1, Mode: os.ModeSetuid | os.ModeSticky | 0755}, false},
+		{map[string]string{"uid": "-1"}, rootOptions{}, true},
+		{map[string]string{"gid": "users"}, rootOptions{}, true},
+		{map[string]string{"mode": "0999"}, rootOptions{}, true},
+		{map[string]string{"mode": "17777"}, rootOptions{}, true},
+		{map[string]string{"mkdir": "sure"}, rootOptions{}, true},
+		{map[string]string{"ro": "true", "mkdir": "true"}, rootOptions{UID: -1, GID: -1, Mkdir: true}, false},
+		{map[string]string{"ro": "true", "uid": "1000"}, rootOptions{}, true},
+		{map[string]string{"ro": "true", "mode": "0755"}, rootOptions{}, true},
+	}
+	for _, test := range tt {
+		r, err := parseRootOptions(test.opts)
+		if (err != nil) != test.err || (!test.err && r != test.result) {
+			t.Errorf("Expected %v (error: %v), got %v (%v)", test.result, test.err, r, err)
+		}
+	}
+}
+
+func TestRootOptionsApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, mode := range []string{"0750", "2770", "1777"} {
+		r, err := parseRootOptions(map[string]string{"uid": strconv.Itoa(os.Getuid()), "gid": strconv.Itoa(os.Getgid()), "mode": mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.apply(dir); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky) != r.Mode {
+			t.Errorf("Expected mode %s to be applied as %v, got %v", mode, r.Mode, fi.Mode())
+		}
+	}
+}
+
+func TestRootOptionsFirstMount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	_, restore := captureCommands(nil)
+	defer restore()
+
+	d := &GlusterDriver{}
+	v := &GlusterVolume{VolumeURI: "node:vol", Options: map[string]string{"mode": "2770"}}
+	if err := d.mountVolume(v, dir); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(dir); err != nil || fi.Mode()&os.ModeSetgid == 0 || !v.Initialized {
+		t.Errorf("Expected mode to be applied at first mount, got %v (%v)", fi.Mode(), err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.mountVolume(v, dir); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(dir); err != nil || fi.Mode().Perm() != 0700 {
+		t.Errorf("Expected mode changed after first mount to be kept, got %v (%v)", fi.Mode(), err)
+	}
+}
@@ -0,0 +1,33 @@
+package driver
+
+import (
+	log "github.com/Sirupsen/logrus"
+)
+
+//Shutdown flush the state and, if unmountIdle, unmount the mountpoints not used by any container
+func (d *GlusterDriver) Shutdown(unmountIdle bool) error {
+	mounts, err := listMounts()
+	if err != nil {
+		return err
+	}
+	d.GetLock().Lock()
+	defer d.GetLock().Unlock()
+	if unmountIdle {
+		for _, m := range d.mounts {
+			if m.Connections == 0 && mounts[m.Path] {
+				log.Infof("Unmounting idle %s", m.Path)
+				if err := d.UnmountPath(m.Path); err != nil {
+					log.Warnf("Unable to unmount %s: %v", m.Path, err)
+				}
+			}
+		}
+	}
+	inUse := make(map[string]int)
+	for _, m := range d.mounts {
+		if m.Connections > 0 {
+			inUse[m.Path] = m.Connections
+		}
+	}
+	closeBackends(inUse)
+	return d.SaveConfig()
+}
@@ -0,0 +1,38 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-shutdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, proc string) { CfgFolder, ProcMounts = cfg, proc }(CfgFolder, ProcMounts)
+	CfgFolder = filepath.Join(dir, "cfg")
+	ProcMounts = filepath.Join(dir, "mounts")
+	if err := ioutil.WriteFile(ProcMounts, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := Init(filepath.Join(dir, "root"), false)
+	if err := d.Create(&volume.CreateRequest{Name: "test", Options: map[string]string{"voluri": "node:vol"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(CfgFolder, "persistence.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Shutdown(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Init(filepath.Join(dir, "root"), false).Volume("test"); err != nil {
+		t.Errorf("Expected volume to be persisted, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package driver
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const srvPrefix = "srv+"
+
+//lookupSRV resolver used for srv+ servers (replaced in tests)
+var lookupSRV = net.LookupSRV
+
+//resolveSRV return servers announced by the SRV record ordered by priority then weight.
+//The order is deterministic (no RFC 2782 weighted random selection) as glusterfs try volfile servers in order anyway,
+//and as glusterfs use one port for all volfile servers, records with another port than the preferred one are ignored
+func resolveSRV(name string) ([]Server, error) {
+	_, addrs, err := lookupSRV("", "", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve SRV record %s: %v", name, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("SRV record %s doesn't announce any server", name)
+	}
+	sort.SliceStable(addrs, func(i, j int) bool {
+		if addrs[i].Priority != addrs[j].Priority {
+			return addrs[i].Priority < addrs[j].Priority
+		}
+		return addrs[i].Weight > addrs[j].Weight
+	})
+	servers := make([]Server, 0, len(addrs))
+	for _, a := range addrs {
+		if a.Port != addrs[0].Port {
+			log.Warnf("SRV record %s announce %s on port %d instead of %d, ignoring it", name, a.Target, a.Port, addrs[0].Port)
+			continue
+		}
+		servers = append(servers, Server{Host: strings.TrimSuffix(a.Target, "."), Port: int(a.Port)})
+	}
+	log.Debugf("SRV record %s resolved to %v", name, servers)
+	return servers, nil
+}
+
+//expandSRV replace srv+ servers of voluri by the servers announced in DNS
+func (u *VolURI) expandSRV() error {
+	err := u.mapServers(func(s Server) ([]Server, error) {
+		if !strings.HasPrefix(s.Host, srvPrefix) {
+			return []Server{s}, nil
+		}
+		return resolveSRV(strings.TrimPrefix(s.Host, srvPrefix))
+	})
+	if err != nil {
+		return err
+	}
+	port := 0
+	for _, s := range u.Servers {
+		if s.IsSocket() {
+			continue
+		}
+		p := s.Port
+		if p == 0 {
+			p = DefaultPort
+		}
+		if port != 0 && p != port {
+			return fmt.Errorf("servers resolved from SRV records use port %d and %d, glusterfs only support one volfile server port", port, p)
+		}
+		port = p
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestExpandSRV(t *testing.T) {
+	defer func(f func(string, string, string) (string, []*net.SRV, error)) { lookupSRV = f }(lookupSRV)
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		switch name {
+		case "_glusterd._tcp.storage.example":
+			return "", []*net.SRV{
+				{Target: "node-3.storage.example.", Port: 24008, Priority: 20, Weight: 10},
+				{Target: "node-1.storage.example.", Port: 24007, Priority: 10, Weight: 10},
+				{Target: "node-2.storage.example.", Port: 24007, Priority: 10, Weight: 50},
+			}, nil
+		case "_glusterd._tcp.other.example":
+			return "", []*net.SRV{{Target: "node-4.other.example.", Port: 24008, Priority: 10, Weight: 10}}, nil
+		case "_glusterd._tcp.empty.example":
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("no such host")
+	}
+
+	tt := []struct {
+		value  string
+		result string
+		err    bool
+	}{
+		{"test:volume", "test:volume", false},
+		{"srv+_glusterd._tcp.storage.example:volume", "node-2.storage.example:24007,node-1.storage.example:24007:volume", false},
+		{"test,srv+_glusterd._tcp.storage.example:volume", "test,node-2.storage.example:24007,node-1.storage.example:24007:volume", false},
+		{"srv+_glusterd._tcp.other.example:volume", "node-4.other.example:24008:volume", false},
+		{"test,srv+_glusterd._tcp.other.example:volume", "", true},
+		{"srv+_glusterd._tcp.empty.example:volume", "", true},
+		{"srv+_glusterd._tcp.unknown.example:volume", "", true},
+	}
+	for _, test := range tt {
+		u, err := ParseVolURI(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := ""
+		if err = u.expandSRV(); err == nil {
+			r = u.String()
+		}
+		if err == nil {
+			if _, err := u.Args(); err != nil {
+				t.Errorf("Expected %s to resolve to a mountable voluri, got %v", test.value, err)
+			}
+		}
+		if (err != nil) != test.err || r != test.result {
+			t.Errorf("Expected to be '%v' (error: %v), got '%v' (%v)", test.result, test.err, r, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	//SSLDir folder where glusterfs client look for its default certificates (glusterfs.ca, glusterfs.pem, glusterfs.key)
+	SSLDir = "/etc/ssl"
+	//SecureAccessFile marker file enabling management encryption for glusterfs client
+	SecureAccessFile = "/var/lib/glusterd/secure-access"
+	//DefaultSSL daemon wide certificates used when a volume doesn't define its own
+	DefaultSSL SSLConfig
+)
+
+//SSLConfig certificates used to connect to a gluster cluster with TLS, IO and Mgmt are nil when not set so that defaults apply
+type SSLConfig struct {
+	CA   string `json:"ssl-ca,omitempty" mapstructure:"ssl-ca"`
+	Cert string `json:"ssl-cert,omitempty" mapstructure:"ssl-cert"`
+	Key  string `json:"ssl-key,omitempty" mapstructure:"ssl-key"`
+	IO   *bool  `json:"ssl,omitempty" mapstructure:"ssl"`
+	Mgmt *bool  `json:"ssl-mgmt,omitempty" mapstructure:"ssl-mgmt"`
+}
+
+//Enabled return true if any kind of encryption is requested
+func (c SSLConfig) Enabled() bool {
+	return c.IOEnabled() || c.MgmtEnabled()
+}
+
+//IOEnabled return true if encryption of I/O path is requested
+func (c SSLConfig) IOEnabled() bool {
+	return c.IO != nil && *c.IO
+}
+
+//MgmtEnabled return true if encryption of management connection is requested
+func (c SSLConfig) MgmtEnabled() bool {
+	return c.Mgmt != nil && *c.Mgmt
+}
+
+//merge fill empty or not set values of c with the one of def
+func (c SSLConfig) merge(def SSLConfig) SSLConfig {
+	if c.CA == "" {
+		c.CA = def.CA
+	}
+	if c.Cert == "" {
+		c.Cert = def.Cert
+	}
+	if c.Key == "" {
+		c.Key = def.Key
+	}
+	if c.IO == nil {
+		c.IO = def.IO
+	}
+	if c.Mgmt == nil {
+		c.Mgmt = def.Mgmt
+	}
+	return c
+}
+
+//sslFromOptions extract ssl settings from volume options
+func sslFromOptions(opts map[string]string) (SSLConfig, error) {
+	c := SSLConfig{
+		CA:   opts["ssl-ca"],
+		Cert: opts["ssl-cert"],
+		Key:  opts["ssl-key"],
+	}
+	var err error
+	if c.IO, err = parseOptionalBoolOpt(opts, "ssl"); err != nil {
+		return c, err
+	}
+	if c.Mgmt, err = parseOptionalBoolOpt(opts, "ssl-mgmt"); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+//parseOptionalBoolOpt parse a boolean option, nil if not set so that defaults apply
+func parseOptionalBoolOpt(opts map[string]string, key string) (*bool, error) {
+	if opts[key] == "" {
+		return nil, nil
+	}
+	b, err := parseBoolOpt(opts, key)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+//parseBoolOpt parse a boolean option, accepting also on/off
+func parseBoolOpt(opts map[string]string, key string) (bool, error) {
+	val, ok := opts[key]
+	if !ok || val == "" {
+		return false, nil
+	}
+	switch strings.ToLower(val) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("option %s is not a boolean: %s", key, val)
+	}
+	return b, nil
+}
+
+//Validate check that certificates files exist and are valid PEM
+func (c SSLConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.CA == "" || c.Cert == "" || c.Key == "" {
+		return fmt.Errorf("ssl-ca, ssl-cert and ssl-key are required to use ssl")
+	}
+	if err := validateCertFile(c.CA); err != nil {
+		return fmt.Errorf("ssl-ca: %v", err)
+	}
+	if err := validateCertFile(c.Cert); err != nil {
+		return fmt.Errorf("ssl-cert: %v", err)
+	}
+	if err := validateKeyFile(c.Key); err != nil {
+		return fmt.Errorf("ssl-key: %v", err)
+	}
+	return nil
+}
+
+func validateCertFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	found := false
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("%s doesn't contain any PEM certificate", path)
+	}
+	return nil
+}
+
+func validateKeyFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return fmt.Errorf("%s doesn't contain a PEM private key", path)
+	}
+	return nil
+}
+
+//stage copy management certificates where glusterfs expect them and enable secure-access
+func (c SSLConfig) stage(inUse bool) error {
+	if !c.MgmtEnabled() {
+		return nil
+	}
+	files := []struct {
+		src  string
+		dst  string
+		perm os.FileMode
+	}{
+		{c.CA, filepath.Join(SSLDir, "glusterfs.ca"), 0644},
+		{c.Cert, filepath.Join(SSLDir, "glusterfs.pem"), 0644},
+		{c.Key, filepath.Join(SSLDir, "glusterfs.key"), 0600},
+	}
+	if err := os.MkdirAll(SSLDir, 0755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f.src)
+		if err != nil {
+			return err
+		}
+		cur, err := ioutil.ReadFile(f.dst)
+		if err == nil && bytes.Equal(cur, b) {
+			continue
+		}
+		if err == nil && inUse {
+			return fmt.Errorf("%s is already staged with another content and used by a mounted volume", f.dst)
+		}
+		if err := ioutil.WriteFile(f.dst, b, f.perm); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(SecureAccessFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(SecureAccessFile, []byte{}, 0644)
+}
+
+//args return glusterfs arguments enabling encryption of I/O path
+func (c SSLConfig) args() string {
+	if !c.IOEnabled() {
+		return ""
+	}
+	return fmt.Sprintf("--xlator-option='*.transport.socket.ssl-enabled=on' --xlator-option=%s --xlator-option=%s --xlator-option=%s",
+		shellQuote("*.transport.socket.ssl-ca-list="+c.CA), shellQuote("*.transport.socket.ssl-own-cert="+c.Cert), shellQuote("*.transport.socket.ssl-private-key="+c.Key))
+}
+
+//cmdArgs return args prefixed by a space to be appended to a glusterfs command (empty if encryption is not used)
+func (c SSLConfig) cmdArgs() string {
+	if a := c.args(); a != "" {
+		return " " + a
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package driver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestCerts(t *testing.T, dir string) SSLConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gluster-client"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := SSLConfig{
+		CA:   filepath.Join(dir, "ca.pem"),
+		Cert: filepath.Join(dir, "cert.pem"),
+		Key:  filepath.Join(dir, "key.pem"),
+	}
+	crt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	for path, b := range map[string][]byte{
+		c.CA:   crt,
+		c.Cert: crt,
+		c.Key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	} {
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestSSLValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-ssl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := writeTestCerts(t, dir)
+
+	if err := c.Validate(); err != nil {
+		t.Error("Expected disabled ssl to be valid, got ", err)
+	}
+	enabled := true
+	c.IO = &enabled
+	if err := c.Validate(); err != nil {
+		t.Error("Expected valid certificates, got ", err)
+	}
+	bad := c
+	bad.Cert = c.Key
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected key used as certificate to be rejected")
+	}
+	bad = c
+	bad.Key = filepath.Join(dir, "missing.pem")
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected missing key to be rejected")
+	}
+	bad = c
+	bad.CA = ""
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected missing ca to be rejected")
+	}
+}
+
+func TestSSLFromOptions(t *testing.T) {
+	c, err := sslFromOptions(map[string]string{"ssl": "on", "ssl-ca": "/ca.pem"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := true
+	c = c.merge(SSLConfig{CA: "/default-ca.pem", Cert: "/cert.pem", Mgmt: &enabled})
+	if !c.IOEnabled() || !c.MgmtEnabled() || c.CA != "/ca.pem" || c.Cert != "/cert.pem" {
+		t.Error("Unexpected merged config, got ", c)
+	}
+	c, err = sslFromOptions(map[string]string{"ssl": "off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c = c.merge(SSLConfig{IO: &enabled, Mgmt: &enabled}); c.IOEnabled() || !c.MgmtEnabled() {
+		t.Error("Expected volume to disable I/O encryption enabled by default, got ", c)
+	}
+	if _, err := sslFromOptions(map[string]string{"ssl": "maybe"}); err == nil {
+		t.Error("Expected invalid boolean to be rejected")
+	}
+}
+
+func TestSSLStage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-ssl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(sslDir, secureAccess string) { SSLDir, SecureAccessFile = sslDir, secureAccess }(SSLDir, SecureAccessFile)
+	SSLDir, SecureAccessFile = filepath.Join(dir, "ssl"), filepath.Join(dir, "glusterd", "secure-access")
+	c := writeTestCerts(t, dir)
+	enabled := true
+	c.Mgmt = &enabled
+
+	if err := c.stage(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"glusterfs.ca", "glusterfs.pem", "glusterfs.key", "../glusterd/secure-access"} {
+		if _, err := os.Stat(filepath.Join(SSLDir, f)); err != nil {
+			t.Error("Expected file to be staged, got ", err)
+		}
+	}
+	if err := c.stage(true); err != nil {
+		t.Error("Expected restaging same files to succeed, got ", err)
+	}
+	other := c
+	other.CA = c.Key
+	if err := other.stage(true); err == nil {
+		t.Error("Expected restaging other files while in use to fail")
+	}
+}
+
+func TestSSLArgs(t *testing.T) {
+	c := SSLConfig{CA: "/ca.pem", Cert: "/certs/it's.pem", Key: "/key.pem'; touch /pwned; '"}
+	enabled := true
+	c.IO = &enabled
+	expected := `--xlator-option='*.transport.socket.ssl-enabled=on' --xlator-option='*.transport.socket.ssl-ca-list=/ca.pem' --xlator-option='*.transport.socket.ssl-own-cert=/certs/it'\''s.pem' --xlator-option='*.transport.socket.ssl-private-key=/key.pem'\''; touch /pwned; '\'''`
+	if a := c.args(); a != expected {
+		t.Errorf("Expected paths to be shell quoted, got %s", a)
+	}
+	out, err := exec.Command("sh", "-c", "printf '%s\\n' "+c.args()).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(out)), "\n"); len(lines) != 4 || lines[3] != "--xlator-option=*.transport.socket.ssl-private-key="+c.Key {
+		t.Errorf("Expected shell to see 4 arguments with the raw key path, got %q", lines)
+	}
+}
@@ -0,0 +1,60 @@
+package driver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+//templateSuffixSep separator of the suffix of a volume name in templates (data-1 -> data and 1)
+const templateSuffixSep = "-"
+
+//templateVars value of the placeholders of a voluri for a volume name
+func templateVars(name string) map[string]string {
+	vars := map[string]string{"name": name, "name_prefix": name, "name_suffix": ""}
+	if i := strings.LastIndex(name, templateSuffixSep); i >= 0 {
+		vars["name_prefix"], vars["name_suffix"] = name[:i], name[i+len(templateSuffixSep):]
+	}
+	return vars
+}
+
+//ExpandVolURI substitute the placeholders of a voluri with the volume name:
+//${name} the name, ${name_prefix} and ${name_suffix} the name before and after its last dash, $$ a literal $ (a $ followed by anything else is kept as is)
+func ExpandVolURI(volURI, name string) (string, error) {
+	vars := templateVars(name)
+	var b bytes.Buffer
+	for i := 0; i < len(volURI); i++ {
+		if volURI[i] != '$' || i+1 == len(volURI) {
+			b.WriteByte(volURI[i])
+			continue
+		}
+		switch volURI[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(volURI[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder in voluri %s", volURI)
+			}
+			key := volURI[i+2 : i+end]
+			val, ok := vars[key]
+			if !ok {
+				return "", fmt.Errorf("unknown placeholder ${%s} in voluri (name, name_prefix, name_suffix)", key)
+			}
+			if val == "" {
+				return "", fmt.Errorf("${%s} is empty for volume name %s", key, name)
+			}
+			b.WriteString(val)
+			i += end
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+//escapeVolURI escape the $ of an already expanded voluri so that ExpandVolURI keep it as is
+func escapeVolURI(volURI string) string {
+	return strings.Replace(volURI, "$", "$$", -1)
+}
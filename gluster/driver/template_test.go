@@ -0,0 +1,65 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+func TestExpandVolURI(t *testing.T) {
+	for _, test := range []struct {
+		voluri, name, expected string
+	}{
+		{"node:vol", "data-1", "node:vol"},
+		{"node:vol/${name}", "data-1", "node:vol/data-1"},
+		{"node:vol-${name_suffix}/${name_prefix}", "stack_data-2", "node:vol-2/stack_data"},
+		{"node:vol/${name_prefix}", "data", "node:vol/data"},
+		{"node:vol/a$$b/$x", "data-1", "node:vol/a$b/$x"},
+		{"node:vol/end$", "data-1", "node:vol/end$"},
+	} {
+		if res, err := ExpandVolURI(test.voluri, test.name); err != nil || res != test.expected {
+			t.Errorf("Expected %s with %s to be %s, got %s (%v)", test.voluri, test.name, test.expected, res, err)
+		}
+	}
+	for _, test := range []struct {
+		voluri, name string
+	}{
+		{"node:vol/${name", "data-1"},
+		{"node:vol/${slot}", "data-1"},
+		{"node:vol/${name_suffix}", "data"},
+		{"node:vol/${name_prefix}", "-1"},
+	} {
+		if res, err := ExpandVolURI(test.voluri, test.name); err == nil {
+			t.Errorf("Expected %s with %s to be refused, got %s", test.voluri, test.name, res)
+		}
+	}
+}
+
+func TestCreateTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(cfg string) { CfgFolder = cfg }(CfgFolder)
+	CfgFolder = filepath.Join(dir, "cfg")
+
+	d := Init(filepath.Join(dir, "root"), true)
+	for _, name := range []string{"data-1", "data-2"} {
+		if err := d.Create(&volume.CreateRequest{Name: name, Options: map[string]string{"voluri": "node:vol/${name}"}}); err != nil {
+			t.Fatal(err)
+		}
+		if v := d.volumes[name]; v.VolumeURI != "node:vol/"+name {
+			t.Errorf("Expected voluri of %s to be expanded, got %s", name, v.VolumeURI)
+		}
+	}
+	if d.volumes["data-1"].Mount == d.volumes["data-2"].Mount {
+		t.Error("Expected expanded volumes to have their own mountpoint")
+	}
+	if err := d.Create(&volume.CreateRequest{Name: "data", Options: map[string]string{"voluri": "node:vol/${name_suffix}"}}); err == nil {
+		t.Error("Expected create with empty placeholder to fail")
+	}
+}
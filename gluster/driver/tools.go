@@ -1,6 +1,9 @@
 package driver
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,17 +11,28 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
-	"github.com/docker/go-plugins-helpers/volume"
 )
 
 const (
-	validHostnameRegex = `(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9\-]*[A-Za-z0-9])`
+	mountPrefixMaxLen = 64
+	mountHashLen      = 16
 )
 
+var mountPrefixRe = regexp.MustCompile(`[^A-Za-z0-9_.\-]+`)
+
+//runCommand run a shell command and return its output (replaced in tests to check mount commands)
+var runCommand = func(ctx context.Context, cmd string) ([]byte, error) {
+	return exec.CommandContext(ctx, "sh", "-c", cmd).CombinedOutput()
+}
+
 //GlusterPersistence represent struct of persistence file
 type GlusterPersistence struct {
 	Version int                           `json:"version"`
@@ -28,6 +42,9 @@ type GlusterPersistence struct {
 
 //SaveConfig stroe config/state in file  //TODO put inside common
 func (d *GlusterDriver) SaveConfig() error {
+	if d.persitence == nil {
+		return nil //Stateless mounter
+	}
 	fi, err := os.Lstat(CfgFolder)
 	if os.IsNotExist(err) {
 		if err = os.MkdirAll(CfgFolder, 0700); err != nil {
@@ -41,12 +58,12 @@ func (d *GlusterDriver) SaveConfig() error {
 	}
 	b, err := json.Marshal(GlusterPersistence{Version: CfgVersion, Volumes: d.volumes, Mounts: d.mounts})
 	if err != nil {
-		log.Warn("Unable to encode persistence struct, %v", err)
+		log.Warnf("Unable to encode persistence struct, %v", err)
 	}
 	//log.Debug("Writing persistence struct, %v", b, d.volumes)
 	err = ioutil.WriteFile(CfgFolder+"/persistence.json", b, 0600)
 	if err != nil {
-		log.Warn("Unable to write persistence struct, %v", err)
+		log.Warnf("Unable to write persistence struct, %v", err)
 		return fmt.Errorf("SaveConfig: %s", err)
 	}
 	return nil
@@ -54,8 +71,13 @@ func (d *GlusterDriver) SaveConfig() error {
 
 //RunCmd run deamon in context of this gvfs drive with custome env
 func (d *GlusterDriver) RunCmd(cmd string) error {
-	log.Debugf(cmd)
-	out, err := exec.Command("sh", "-c", cmd).CombinedOutput()
+	log.Debug(redactCmd(cmd))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(MountTimeout)*time.Second)
+	defer cancel()
+	out, err := runCommand(ctx, cmd)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%s timed out after %ds", redactCmd(cmd), MountTimeout)
+	}
 	if err != nil {
 		log.Debugf("Error: %v", err)
 	}
@@ -63,22 +85,153 @@ func (d *GlusterDriver) RunCmd(cmd string) error {
 	return err
 }
 
-func isValidURI(volURI string) bool {
-	re := regexp.MustCompile(validHostnameRegex + ":.+")
-	return re.MatchString(volURI)
+//mgmtSSLInUse return true if a mounted volume rely on the staged management certificates
+func (d *GlusterDriver) mgmtSSLInUse(clusters map[string]Cluster) bool {
+	for _, v := range d.volumes {
+		if v.Connections == 0 {
+			continue
+		}
+		u, err := v.VolURI()
+		if err != nil {
+			continue
+		}
+		cluster, _ := clusterOf(u, clusters)
+		if c, err := v.SSL(cluster); err == nil && c.MgmtEnabled() {
+			return true
+		}
+	}
+	return false
 }
 
-func parseVolURI(volURI string) string {
-	volParts := strings.Split(volURI, ":")
-	volServers := strings.Split(volParts[0], ",")
-	return fmt.Sprintf("--volfile-id='%s' -s '%s'", volParts[1], strings.Join(volServers, "' -s '"))
+//getMountName return the name of the mountpoint of a volume, with mountUniqName it is shared by volumes of same definition
+func getMountName(d *GlusterDriver, name string, v *GlusterVolume) string {
+	if d.mountUniqName {
+		return uniqMountName(v)
+	}
+	return url.PathEscape(name)
 }
 
-func getMountName(d *GlusterDriver, r *volume.CreateRequest) string {
-	if d.mountUniqName {
-		return url.PathEscape(r.Options["voluri"])
+//uniqMountName return a fixed length name made of a readable prefix and a hash of the canonical voluri and options
+func uniqMountName(v *GlusterVolume) string {
+	canonical := v.VolumeURI
+	prefix := "gluster"
+	if u, err := v.VolURI(); err == nil {
+		canonical = u.String()
+		prefix = mountPrefixRe.ReplaceAllString(u.Volume+u.Subdir, "_")
+	}
+	keys := make([]string, 0, len(v.Options))
+	for k := range v.Options {
+		if k != "transport" { //Already part of canonical voluri
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	io.WriteString(h, canonical)
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00%s=%s", k, v.Options[k])
+	}
+	if len(prefix) > mountPrefixMaxLen {
+		prefix = prefix[:mountPrefixMaxLen]
+	}
+	return prefix + "-" + hex.EncodeToString(h.Sum(nil))[:mountHashLen]
+}
+
+//migrateMountNames move volumes to the mountpoint name of the current scheme, mountpoints in use are migrated on a later start
+func (d *GlusterDriver) migrateMountNames() error {
+	mounts, err := listMounts()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for name, v := range d.volumes {
+		newName := getMountName(d, name, v)
+		if v.Mount == newName {
+			continue
+		}
+		old, hasOld := d.mounts[v.Mount]
+		if hasOld && (old.Connections > 0 || mounts[old.Path]) {
+			log.Warnf("Mountpoint %s of volume %s is in use, it will be renamed on a later start", old.Path, name)
+			continue
+		}
+		if _, ok := d.mounts[newName]; !ok {
+			m := &GlusterMountpoint{Path: filepath.Join(d.root, newName)}
+			if err := moveMountpoint(old, m.Path); err != nil {
+				return err
+			}
+			d.mounts[newName] = m
+		}
+		log.Infof("Migrating mountpoint of volume %s from %s to %s", name, v.Mount, newName)
+		v.Mount = newName
+		changed = true
 	}
-	return url.PathEscape(r.Name)
+	if !changed {
+		return nil
+	}
+	for name, m := range d.mounts { //Remove mountpoints not used anymore
+		if d.volumeOfMount(name) == nil && m.Connections == 0 && !mounts[m.Path] {
+			if err := os.Remove(m.Path); err != nil && !os.IsNotExist(err) {
+				log.Warnf("Unable to remove old mountpoint %s: %v", m.Path, err)
+				continue
+			}
+			delete(d.mounts, name)
+		}
+	}
+	return d.SaveConfig()
+}
+
+//moveMountpoint rename the folder of the old mountpoint to path if possible or else create path
+func moveMountpoint(old *GlusterMountpoint, path string) error {
+	if old != nil {
+		_, errOld := os.Lstat(old.Path)
+		_, errNew := os.Lstat(path)
+		if errOld == nil && os.IsNotExist(errNew) {
+			return os.Rename(old.Path, path)
+		}
+	}
+	return os.MkdirAll(path, 0700)
+}
+
+//isMounted return true if path is a mountpoint
+func isMounted(path string) (bool, error) {
+	mounts, err := listMounts()
+	if err != nil {
+		return false, err
+	}
+	return mounts[filepath.Clean(path)], nil
+}
+
+//listMounts return the mountpoints of the system
+func listMounts() (map[string]bool, error) {
+	b, err := ioutil.ReadFile(ProcMounts)
+	if err != nil {
+		return nil, err
+	}
+	mounts := make(map[string]bool)
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		mounts[unescapeMount(fields[1])] = true
+	}
+	return mounts, nil
+}
+
+//unescapeMount decode octal escapes (\040 for space, ...) of /proc/mounts
+func unescapeMount(s string) string {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				out = append(out, byte(n))
+				i += 3
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
 }
 
 //based on: http://stackoverflow.com/questions/30697324/how-to-check-if-directory-on-path-is-empty
@@ -95,3 +248,8 @@ func isEmpty(name string) (bool, error) {
 	}
 	return false, err // Either not empty or error, suits both cases
 }
+
+//IsMounted return true if path is a mountpoint
+func (d *GlusterDriver) IsMounted(path string) (bool, error) {
+	return isMounted(path)
+}
@@ -1,78 +1,130 @@
 package driver
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
-
-	"github.com/docker/go-plugins-helpers/volume"
 )
 
-func TestIsValidURI(t *testing.T) {
-	tt := []struct {
-		value  string
-		result bool
-	}{
-		{"test", false},
-		{"test:volume", true},
-		{"test;volume", false},
-		{"test,volume", false},
-		{"test,test2:volume", true},
-		{"192.168.1.1:volume", true},
-		{"192.168.1.:volume", false},
-		{"192.168.1.1,10.8.0.1:volume", true},
-		{"192.168.1.1,test2:volume", true},
+func TestMountName(t *testing.T) {
+	v := &GlusterVolume{VolumeURI: "gluster-node:volname"}
+	name := getMountName(&GlusterDriver{mountUniqName: false}, "test", v)
+	if name != "test" {
+		t.Error("Expected to be test, got ", name)
 	}
 
-	for _, test := range tt {
-		r := isValidURI(test.value)
-		if test.result != r {
-			t.Errorf("Expected to be '%v' , got '%v'", test.result, r)
-		}
+	uniq := &GlusterDriver{mountUniqName: true}
+	nameuniq := getMountName(uniq, "test", v)
+	if !regexp.MustCompile(`^volname-[0-9a-f]{16}$`).MatchString(nameuniq) {
+		t.Error("Expected to be volname-<hash>, got ", nameuniq)
 	}
-}
-func TestParseVolURI(t *testing.T) {
-	tt := []struct {
-		value  string
-		result string
-	}{
-		{"test:volume", "--volfile-id='volume' -s 'test'"},
-		{"test,test2:volume", "--volfile-id='volume' -s 'test' -s 'test2'"},
-		{"192.168.1.1:volume", "--volfile-id='volume' -s '192.168.1.1'"},
-		{"192.168.1.1,10.8.0.1:volume", "--volfile-id='volume' -s '192.168.1.1' -s '10.8.0.1'"},
-		{"192.168.1.1,test2:volume", "--volfile-id='volume' -s '192.168.1.1' -s 'test2'"},
+	if n := getMountName(uniq, "other", &GlusterVolume{VolumeURI: "gluster-node:volname/"}); n != nameuniq {
+		t.Errorf("Expected same definition to share mountpoint %s, got %s", nameuniq, n)
 	}
 
-	for _, test := range tt {
-		r := parseVolURI(test.value)
-		if test.result != r {
-			t.Errorf("Expected to be '%v' , got '%v'", test.result, r)
+	names := map[string]bool{nameuniq: true}
+	for _, other := range []*GlusterVolume{
+		{VolumeURI: "gluster-node:volname", Options: map[string]string{"ro": "true"}},
+		{VolumeURI: "gluster-node:volname", Options: map[string]string{"uid": "1000"}},
+		{VolumeURI: "gluster-node:volname/sub"},
+		{VolumeURI: "gluster-node:volname", Options: map[string]string{"transport": "rdma"}},
+	} {
+		n := getMountName(uniq, "test", other)
+		if names[n] {
+			t.Errorf("Expected %v to have its own mountpoint, got %s", other, n)
 		}
+		names[n] = true
+	}
+	if n := getMountName(uniq, "test", &GlusterVolume{VolumeURI: "gluster-node:volname/sub"}); !strings.HasPrefix(n, "volname_sub-") {
+		t.Error("Expected readable prefix volname_sub-, got ", n)
 	}
-}
 
-func TestMountName(t *testing.T) {
-	name := getMountName(&GlusterDriver{
-		mountUniqName: false,
-	}, &volume.CreateRequest{
-		Name: "test",
-		Options: map[string]string{
-			"voluri": "gluster-node:volname",
-		},
-	})
+	long := &GlusterVolume{VolumeURI: strings.Repeat("gluster-node-with-a-long-name,", 20) + "last:" + strings.Repeat("v", 300)}
+	if n := getMountName(uniq, "test", long); len(n) > mountPrefixMaxLen+1+mountHashLen {
+		t.Errorf("Expected mountpoint name to be length-safe, got %d chars", len(n))
+	}
+}
 
-	if name != "test" {
-		t.Error("Expected to be test, got ", name)
+func TestMigrateMountNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-migrate")
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer os.RemoveAll(dir)
+	defer func(cfg, proc string) { CfgFolder, ProcMounts = cfg, proc }(CfgFolder, ProcMounts)
+	CfgFolder = filepath.Join(dir, "cfg")
+	ProcMounts = filepath.Join(dir, "mounts")
+	root := filepath.Join(dir, "root")
 
-	nameuniq := getMountName(&GlusterDriver{
-		mountUniqName: true,
-	}, &volume.CreateRequest{
-		Name: "test",
-		Options: map[string]string{
-			"voluri": "gluster-node:volname",
+	old := filepath.Join(root, "gluster-node:volname")
+	used := filepath.Join(root, "gluster-node:used")
+	for _, p := range []string{old, used} {
+		if err := os.MkdirAll(p, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(ProcMounts, []byte("gluster-node:used "+used+" fuse.glusterfs rw 0 0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d := &GlusterDriver{root: root, mountUniqName: true,
+		volumes: map[string]*GlusterVolume{
+			"a":    {VolumeURI: "gluster-node:volname", Mount: "gluster-node:volname"},
+			"b":    {VolumeURI: "gluster-node:volname", Mount: "gluster-node:volname", Options: map[string]string{"uid": "1000"}},
+			"used": {VolumeURI: "gluster-node:used", Mount: "gluster-node:used", Connections: 1},
+		},
+		mounts: map[string]*GlusterMountpoint{
+			"gluster-node:volname": {Path: old},
+			"gluster-node:used":    {Path: used, Connections: 1},
 		},
-	})
+	}
+	if err := d.migrateMountNames(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		v := d.volumes[name]
+		if v.Mount != uniqMountName(v) {
+			t.Errorf("Expected %s to be migrated, got %s", name, v.Mount)
+		}
+		if _, err := os.Stat(filepath.Join(root, v.Mount)); err != nil {
+			t.Errorf("Expected mountpoint of %s to exist: %v", name, err)
+		}
+	}
+	if d.volumes["a"].Mount == d.volumes["b"].Mount {
+		t.Error("Expected volumes with different options to not share mountpoint")
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected old mountpoint to be removed")
+	}
+	if _, ok := d.mounts["gluster-node:volname"]; ok {
+		t.Error("Expected old mountpoint to be forgotten")
+	}
+	if d.volumes["used"].Mount != "gluster-node:used" {
+		t.Error("Expected mountpoint in use to not be migrated")
+	}
+}
 
-	if nameuniq != "gluster-node:volname" {
-		t.Error("Expected to be gluster-node:volname, got ", name)
+func TestIsMounted(t *testing.T) {
+	f, err := ioutil.TempFile("", "gluster-mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer func(p string) { ProcMounts = p }(ProcMounts)
+	ProcMounts = f.Name()
+	f.WriteString("proc /proc proc rw 0 0\nnode:volume /var/lib/docker-volumes/gluster/with\\040space fuse.glusterfs rw 0 0\n")
+	f.Close()
+
+	for path, result := range map[string]bool{
+		"/proc": true,
+		"/var/lib/docker-volumes/gluster/with space":  true,
+		"/var/lib/docker-volumes/gluster/with space/": true,
+		"/var/lib/docker-volumes/gluster/other":       false,
+	} {
+		if mounted, err := isMounted(path); err != nil || mounted != result {
+			t.Errorf("Expected %s mounted to be %v, got %v (%v)", path, result, mounted, err)
+		}
 	}
 }
@@ -0,0 +1,325 @@
+package driver
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	validHostnameRegex = `(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9\-]*[A-Za-z0-9])`
+	validClusterRegex  = `@[A-Za-z0-9][A-Za-z0-9_\-]*`
+	validSRVRegex      = `srv\+[A-Za-z0-9_][A-Za-z0-9_.\-]*`
+	validVolumeRegex   = `[A-Za-z0-9_.\-]+`
+	validOptionRegex   = `[a-z][a-z0-9\-]*`
+	validSocketRegex   = `/[A-Za-z0-9_.\-/]+`
+	//DefaultPort default port of glusterd
+	DefaultPort = 24007
+)
+
+var (
+	hostnameRe = regexp.MustCompile("^(" + validClusterRegex + "|" + validSRVRegex + "|" + validHostnameRegex + ")$")
+	volumeRe   = regexp.MustCompile("^" + validVolumeRegex + "(/.*)?$")
+	optionRe   = regexp.MustCompile("^" + validOptionRegex + "$")
+	socketRe   = regexp.MustCompile("^" + validSocketRegex + "$")
+	//Transports supported by glusterfs to fetch volfile
+	Transports = []string{"tcp", "rdma", "unix"}
+)
+
+//Server a volfile server of a voluri (Host is the path of the socket for unix transport)
+type Server struct {
+	Host string
+	Port int
+}
+
+//IsSocket return true if the server is a unix socket
+func (s Server) IsSocket() bool {
+	return strings.HasPrefix(s.Host, "/")
+}
+
+//String return the server as it would be written in a voluri
+func (s Server) String() string {
+	host := s.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if s.Port != 0 {
+		return host + ":" + strconv.Itoa(s.Port)
+	}
+	return host
+}
+
+//VolURI parsed representation of a voluri : server[,server...]:volume[/subdir][?option=value[&option=value...]]
+type VolURI struct {
+	Servers   []Server
+	Transport string
+	Volume    string
+	Subdir    string
+	Options   map[string]string
+}
+
+//ParseVolURI parse a voluri
+func ParseVolURI(volURI string) (*VolURI, error) {
+	u := &VolURI{Options: make(map[string]string)}
+	rest := volURI
+	if i := strings.Index(rest, "?"); i >= 0 {
+		query, err := url.ParseQuery(rest[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid options in voluri: %v", err)
+		}
+		for k, vals := range query {
+			if !optionRe.MatchString(k) {
+				return nil, fmt.Errorf("invalid option name in voluri: %s", k)
+			}
+			if len(vals) > 1 {
+				return nil, fmt.Errorf("option %s is defined multiple times in voluri", k)
+			}
+			u.Options[k] = vals[0]
+		}
+		rest = rest[:i]
+	}
+	if t, ok := u.Options["transport"]; ok {
+		u.Transport = t
+		delete(u.Options, "transport")
+	}
+
+	//Try each top-level colon until the left part is a valid server list and the right part a volume
+	depth := 0
+	for i, c := range rest {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth != 0 || !volumeRe.MatchString(rest[i+1:]) {
+				continue
+			}
+			servers, err := parseServers(rest[:i])
+			if err != nil {
+				continue
+			}
+			u.Servers = servers
+			volParts := strings.SplitN(rest[i+1:], "/", 2)
+			u.Volume = volParts[0]
+			if len(volParts) > 1 {
+				if sub := path.Clean("/" + volParts[1]); sub != "/" {
+					u.Subdir = sub
+				}
+			}
+			return u, nil
+		}
+	}
+	if _, err := parseServers(strings.SplitN(rest, ":", 2)[0]); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("voluri %s doesn't match server[,server...]:volume[/subdir]", volURI)
+}
+
+func parseServers(list string) ([]Server, error) {
+	var servers []Server
+	for _, s := range splitServers(list) {
+		server, err := parseServer(s)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+//splitServers split a server list on commas outside of brackets
+func splitServers(list string) []string {
+	var servers []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				servers = append(servers, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(servers, list[start:])
+}
+
+func parseServer(s string) (Server, error) {
+	if strings.HasPrefix(s, "/") {
+		if !socketRe.MatchString(s) {
+			return Server{}, fmt.Errorf("invalid socket path %s", s)
+		}
+		return Server{Host: path.Clean(s)}, nil
+	}
+	if strings.HasPrefix(s, "[") {
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return Server{}, fmt.Errorf("invalid server %s: missing ]", s)
+		}
+		ip := net.ParseIP(s[1:end])
+		if ip == nil || ip.To4() != nil {
+			return Server{}, fmt.Errorf("invalid server %s: not an IPv6 address", s)
+		}
+		server := Server{Host: ip.String()}
+		if rest := s[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return Server{}, fmt.Errorf("invalid server %s", s)
+			}
+			port, err := parsePort(rest[1:])
+			if err != nil {
+				return Server{}, fmt.Errorf("invalid server %s: %v", s, err)
+			}
+			server.Port = port
+		}
+		return server, nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+	if !hostnameRe.MatchString(parts[0]) {
+		return Server{}, fmt.Errorf("invalid server %s", s)
+	}
+	server := Server{Host: parts[0]}
+	if len(parts) > 1 {
+		port, err := parsePort(parts[1])
+		if err != nil {
+			return Server{}, fmt.Errorf("invalid server %s: %v", s, err)
+		}
+		server.Port = port
+	}
+	return server, nil
+}
+
+func parsePort(p string) (int, error) {
+	port, err := strconv.Atoi(p)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %s", p)
+	}
+	return port, nil
+}
+
+//String return the canonical form of the voluri
+func (u *VolURI) String() string {
+	servers := make([]string, len(u.Servers))
+	for i, s := range u.Servers {
+		servers[i] = s.String()
+	}
+	str := strings.Join(servers, ",") + ":" + u.Volume + u.Subdir
+	query := url.Values{}
+	for k, val := range u.Options {
+		query.Set(k, val)
+	}
+	if u.Transport != "" {
+		query.Set("transport", u.Transport)
+	}
+	if len(query) > 0 {
+		str += "?" + query.Encode()
+	}
+	return str
+}
+
+//transport return the transport to use to fetch volfile checking it is coherent with servers
+func (u *VolURI) transport() (string, error) {
+	sockets := 0
+	for _, s := range u.Servers {
+		if s.IsSocket() {
+			sockets++
+		}
+	}
+	if sockets > 0 && sockets != len(u.Servers) {
+		return "", fmt.Errorf("unix socket can't be mixed with network servers")
+	}
+	t := u.Transport
+	if t == "" && sockets > 0 {
+		t = "unix"
+	}
+	if t == "" {
+		return "", nil
+	}
+	valid := false
+	for _, known := range Transports {
+		valid = valid || t == known
+	}
+	if !valid {
+		return "", fmt.Errorf("unsupported transport %s (supported: %s)", t, strings.Join(Transports, ", "))
+	}
+	if (t == "unix") != (sockets > 0) {
+		return "", fmt.Errorf("transport unix need socket path as server and only it")
+	}
+	return t, nil
+}
+
+//Args return glusterfs arguments to mount the volume
+func (u *VolURI) Args() (string, error) {
+	args := []string{"--volfile-id=" + shellQuote(u.Volume)}
+	transport, err := u.transport()
+	if err != nil {
+		return "", err
+	}
+	port := 0
+	for _, s := range u.Servers {
+		if s.IsSocket() {
+			if s.Port != 0 {
+				return "", fmt.Errorf("unix socket %s can't have a port", s.Host)
+			}
+			args = append(args, "-s "+shellQuote(s.Host))
+			continue
+		}
+		p := s.Port
+		if p == 0 {
+			p = DefaultPort
+		}
+		if port != 0 && p != port {
+			return "", fmt.Errorf("glusterfs only support one volfile server port, got %d and %d", port, p)
+		}
+		port = p
+		args = append(args, "-s "+shellQuote(s.Host))
+	}
+	if port != 0 && port != DefaultPort {
+		args = append(args, fmt.Sprintf("--volfile-server-port=%d", port))
+	}
+	if transport != "" {
+		args = append(args, "--volfile-server-transport="+transport)
+	}
+	if u.Subdir != "" {
+		args = append(args, "--subdir-mount="+shellQuote(u.Subdir))
+	}
+	keys := make([]string, 0, len(u.Options))
+	for k := range u.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if u.Options[k] == "" {
+			args = append(args, "--"+k)
+		} else {
+			args = append(args, "--"+k+"="+shellQuote(u.Options[k]))
+		}
+	}
+	return strings.Join(args, " "), nil
+}
+
+//mapServers replace each server of voluri by the list returned by fn
+func (u *VolURI) mapServers(fn func(Server) ([]Server, error)) error {
+	var servers []Server
+	for _, s := range u.Servers {
+		list, err := fn(s)
+		if err != nil {
+			return err
+		}
+		servers = append(servers, list...)
+	}
+	u.Servers = servers
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
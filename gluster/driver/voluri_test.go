@@ -0,0 +1,164 @@
+package driver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsValidURI(t *testing.T) {
+	tt := []struct {
+		value  string
+		result bool
+	}{
+		{"test", false},
+		{"test:volume", true},
+		{"test;volume", false},
+		{"test,volume", false},
+		{"test,test2:volume", true},
+		{"192.168.1.1:volume", true},
+		{"192.168.1.:volume", false},
+		{"192.168.1.1,10.8.0.1:volume", true},
+		{"192.168.1.1,test2:volume", true},
+		{"@prod:volume", true},
+		{"@:volume", false},
+		{"srv+_glusterd._tcp.storage.example:volume", true},
+		{"[fd00::1]:volume", true},
+		{"[fd00::1]:24008:volume", true},
+		{"fd00::1:volume", false},
+		{"[192.168.1.1]:volume", false},
+		{"[fd00::1:volume", false},
+		{"test:24008:volume", true},
+		{"test:0:volume", false},
+		{"test:65536:volume", false},
+		{"test:port:volume", false},
+		{"test:", false},
+		{":volume", false},
+		{"test:volume/sub/dir", true},
+		{"test:volume?log-level=WARNING", true},
+		{"test:volume?Bad_Option=1", false},
+		{"test:volume?a=1&a=2", false},
+		{"test:vol:ume", false},
+		{"/var/run/glusterd.socket:volume", true},
+		{"/var/run/glusterd socket:volume", false},
+	}
+
+	for _, test := range tt {
+		_, err := ParseVolURI(test.value)
+		if test.result != (err == nil) {
+			t.Errorf("Expected '%s' to be valid: '%v' , got '%v'", test.value, test.result, err)
+		}
+	}
+}
+
+func TestParseVolURIStruct(t *testing.T) {
+	tt := []struct {
+		value  string
+		result VolURI
+	}{
+		{"test:volume", VolURI{Servers: []Server{{Host: "test"}}, Volume: "volume", Options: map[string]string{}}},
+		{"test:24008,[fd00::1]:volume", VolURI{Servers: []Server{{Host: "test", Port: 24008}, {Host: "fd00::1"}}, Volume: "volume", Options: map[string]string{}}},
+		{"[FD00:0::1]:24008:volume/sub//dir/", VolURI{Servers: []Server{{Host: "fd00::1", Port: 24008}}, Volume: "volume", Subdir: "/sub/dir", Options: map[string]string{}}},
+		{"test:volume/", VolURI{Servers: []Server{{Host: "test"}}, Volume: "volume", Options: map[string]string{}}},
+		{"test:volume/a:b", VolURI{Servers: []Server{{Host: "test"}}, Volume: "volume", Subdir: "/a:b", Options: map[string]string{}}},
+		{"test:volume?transport=rdma&log-level=WARNING&acl", VolURI{Servers: []Server{{Host: "test"}}, Volume: "volume", Transport: "rdma", Options: map[string]string{"log-level": "WARNING", "acl": ""}}},
+		{"@prod,srv+_glusterd._tcp.example:volume", VolURI{Servers: []Server{{Host: "@prod"}, {Host: "srv+_glusterd._tcp.example"}}, Volume: "volume", Options: map[string]string{}}},
+	}
+
+	for _, test := range tt {
+		r, err := ParseVolURI(test.value)
+		if err != nil {
+			t.Errorf("Expected '%s' to be valid, got %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(*r, test.result) {
+			t.Errorf("Expected to be '%+v' , got '%+v'", test.result, *r)
+		}
+	}
+}
+
+func TestVolURIString(t *testing.T) {
+	tt := []struct {
+		value  string
+		result string
+	}{
+		{"gluster-node:volname", "gluster-node:volname"},
+		{"test,test2:volume", "test,test2:volume"},
+		{"[fd00:0::1]:24008:volume/sub/", "[fd00::1]:24008:volume/sub"},
+		{"test:volume?transport=rdma&b=2&a=1", "test:volume?a=1&b=2&transport=rdma"},
+	}
+
+	for _, test := range tt {
+		u, err := ParseVolURI(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := u.String(); test.result != r {
+			t.Errorf("Expected to be '%v' , got '%v'", test.result, r)
+		}
+	}
+}
+
+func TestParseVolURI(t *testing.T) {
+	tt := []struct {
+		value  string
+		result string
+	}{
+		{"test:volume", "--volfile-id='volume' -s 'test'"},
+		{"test,test2:volume", "--volfile-id='volume' -s 'test' -s 'test2'"},
+		{"192.168.1.1:volume", "--volfile-id='volume' -s '192.168.1.1'"},
+		{"192.168.1.1,10.8.0.1:volume", "--volfile-id='volume' -s '192.168.1.1' -s '10.8.0.1'"},
+		{"192.168.1.1,test2:volume", "--volfile-id='volume' -s '192.168.1.1' -s 'test2'"},
+		{"[fd00::1],[fd00::2]:volume", "--volfile-id='volume' -s 'fd00::1' -s 'fd00::2'"},
+		{"test:24007,test2:volume", "--volfile-id='volume' -s 'test' -s 'test2'"},
+		{"test:24008,test2:24008:volume", "--volfile-id='volume' -s 'test' -s 'test2' --volfile-server-port=24008"},
+		{"test:volume/sub/dir", "--volfile-id='volume' -s 'test' --subdir-mount='/sub/dir'"},
+		{"test:volume?log-level=WARNING&acl", "--volfile-id='volume' -s 'test' --acl --log-level='WARNING'"},
+		{"test:volume?log-file=/tmp/it's.log", `--volfile-id='volume' -s 'test' --log-file='/tmp/it'\''s.log'`},
+		{"test:volume?transport=rdma", "--volfile-id='volume' -s 'test' --volfile-server-transport=rdma"},
+		{"test:volume?transport=tcp", "--volfile-id='volume' -s 'test' --volfile-server-transport=tcp"},
+		{"/var/run/glusterd.socket:volume", "--volfile-id='volume' -s '/var/run/glusterd.socket' --volfile-server-transport=unix"},
+		{"/var/run/glusterd.socket:volume/sub?transport=unix", "--volfile-id='volume' -s '/var/run/glusterd.socket' --volfile-server-transport=unix --subdir-mount='/sub'"},
+	}
+
+	for _, test := range tt {
+		u, err := ParseVolURI(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := u.Args()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.result != r {
+			t.Errorf("Expected to be '%v' , got '%v'", test.result, r)
+		}
+	}
+
+	for _, invalid := range []string{
+		"test:24008,test2:volume",
+		"test:volume?transport=udp",
+		"test:volume?transport=unix",
+		"/var/run/glusterd.socket:volume?transport=rdma",
+		"/var/run/glusterd.socket,test:volume",
+	} {
+		u, err := ParseVolURI(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := u.Args(); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestVolumeTransport(t *testing.T) {
+	v := &GlusterVolume{VolumeURI: "test:volume", Options: map[string]string{"transport": "rdma"}}
+	u, err := v.VolURI()
+	if err != nil || u.Transport != "rdma" {
+		t.Error("Expected transport option to be applied, got ", u, err)
+	}
+	v.VolumeURI = "test:volume?transport=tcp"
+	if _, err := v.VolURI(); err == nil {
+		t.Error("Expected conflicting transport to be rejected")
+	}
+}
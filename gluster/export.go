@@ -0,0 +1,106 @@
+package gluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/cobra"
+)
+
+const (
+	//DryRunFlag flag to only show what import would do
+	DryRunFlag = "dry-run"
+	//ConflictFlag flag to set how import handle volumes that already exist with another definition
+	ConflictFlag = "conflict"
+)
+
+var (
+	importDryRun   = false
+	importConflict = driver.ConflictSkip
+	exportCmd      = &cobra.Command{
+		Use:   "export",
+		Short: "Write definitions of all volumes as json on stdout",
+		Args:  cobra.NoArgs,
+		RunE:  exportVolumes,
+	}
+	importCmd = &cobra.Command{
+		Use:   "import FILE",
+		Short: "Create volumes from a file written by export (- for stdin)",
+		Args:  cobra.ExactArgs(1),
+		RunE:  importVolumes,
+	}
+)
+
+func setupExportCmd() {
+	importCmd.Flags().BoolVar(&importDryRun, DryRunFlag, false, "Only show what would be done")
+	importCmd.Flags().StringVar(&importConflict, ConflictFlag, importConflict, "How to handle volumes that already exist with another definition (skip, overwrite or rename)")
+	importCmd.Flags().StringVar(&outputFormat, FormatFlag, outputFormat, "Output format (table or json)")
+}
+
+func exportVolumes(cmd *cobra.Command, args []string) error {
+	d, err := adminDriver(false)
+	if err != nil {
+		return err
+	}
+	e, err := d.Export()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+func importVolumes(cmd *cobra.Command, args []string) error {
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	var e driver.Export
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return fmt.Errorf("unable to decode %s: %v", args[0], err)
+	}
+	d, err := adminDriver(!importDryRun)
+	if err != nil {
+		return err
+	}
+	results, err := d.Import(&e, importConflict, importDryRun)
+	if err != nil {
+		return err
+	}
+	if err := printImport(cmd.OutOrStdout(), results); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Error != "" {
+			return fmt.Errorf("some volumes could not be imported")
+		}
+	}
+	return nil
+}
+
+func printImport(w io.Writer, results []driver.ImportResult) error {
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tACTION\tAS\tERROR")
+		for _, res := range results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.Name, res.Action, res.As, res.Error)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown format %s (table or json)", outputFormat)
+}
@@ -0,0 +1,58 @@
+package gluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcDryRun = false
+	gcCmd    = &cobra.Command{
+		Use:   "gc",
+		Short: "Remove empty directories under basedir not used by any volume and report non-empty ones",
+		Args:  cobra.NoArgs,
+		RunE:  gc,
+	}
+)
+
+func setupGCCmd() {
+	gcCmd.Flags().BoolVar(&gcDryRun, DryRunFlag, false, "Only show what would be removed")
+	gcCmd.Flags().StringVar(&outputFormat, FormatFlag, outputFormat, "Output format (table or json)")
+}
+
+func gc(cmd *cobra.Command, args []string) error {
+	d, err := adminDriver(!gcDryRun)
+	if err != nil {
+		return err
+	}
+	res, err := d.GC(gcDryRun)
+	if err != nil {
+		return err
+	}
+	return printGC(cmd.OutOrStdout(), res)
+}
+
+func printGC(w io.Writer, res *driver.GCResult) error {
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	case "table":
+		for _, p := range res.Removed {
+			fmt.Fprintf(w, "removed\t%s\n", p)
+		}
+		for _, p := range res.NonEmpty {
+			fmt.Fprintf(w, "not empty\t%s\n", p)
+		}
+		for _, p := range res.Mounted {
+			fmt.Fprintf(w, "mounted\t%s\n", p)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %s (table or json)", outputFormat)
+}
@@ -0,0 +1,6 @@
+//Package gfapi serve gluster volumes with an in-process FUSE filesystem backed by libgfapi.
+//
+//It need cgo and libgfapi headers (glusterfs-api pkg-config) and is only built with the gfapi build tag:
+//
+//	go build -tags gfapi
+package gfapi
@@ -0,0 +1,396 @@
+//go:build gfapi
+// +build gfapi
+
+package gfapi
+
+import (
+	"context"
+	"path"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+//Config how to serve a volume with FUSE
+type Config struct {
+	Volume          string
+	Subdir          string //Served as root of the mount
+	Servers         []Server
+	LogLevel        int
+	XlatorOptions   map[string]map[string]string
+	ReadOnly        bool
+	Mkdir           bool //Create Subdir if it doesn't exist
+	AttrTimeout     time.Duration
+	EntryTimeout    time.Duration
+	NegativeTimeout time.Duration
+	DirectIO        bool
+}
+
+//Stats counters of the operations served by a mount
+type Stats struct {
+	Ops          uint64
+	Errors       uint64
+	ReadBytes    uint64
+	WrittenBytes uint64
+}
+
+//Mount a volume served on a path by the daemon process
+type Mount struct {
+	Path   string
+	Volume string
+	stats  Stats
+	server *fuse.Server
+	vol    *Volume
+}
+
+//NewMount open the volume and serve it on path until Unmount or external unmount
+func NewMount(mountpoint string, c Config) (*Mount, error) {
+	vol, err := Open(c.Volume, c.Servers, c.LogLevel, c.XlatorOptions)
+	if err != nil {
+		return nil, err
+	}
+	base := path.Join("/", c.Subdir)
+	if c.Mkdir && base != "/" {
+		if err := vol.MkdirAll(base, 0755); err != nil {
+			vol.Close()
+			return nil, err
+		}
+	}
+	if _, err := vol.Lstat(base); err != nil {
+		vol.Close()
+		return nil, err
+	}
+	m := &Mount{Path: mountpoint, Volume: c.Volume, vol: vol}
+	opts := &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName:      c.Volume,
+			Name:        "glusterfs",
+			AllowOther:  true,
+			DirectMount: true,
+		},
+		AttrTimeout:     &c.AttrTimeout,
+		EntryTimeout:    &c.EntryTimeout,
+		NegativeTimeout: &c.NegativeTimeout,
+	}
+	//Operations are run by the daemon as root, the kernel need to check permissions of the caller
+	opts.MountOptions.Options = append(opts.MountOptions.Options, "default_permissions")
+	if c.ReadOnly {
+		opts.MountOptions.Options = append(opts.MountOptions.Options, "ro")
+	}
+	root := &node{mount: m, base: base, directIO: c.DirectIO}
+	m.server, err = fs.Mount(mountpoint, root, opts)
+	if err != nil {
+		vol.Close()
+		return nil, err
+	}
+	go func() {
+		m.server.Wait()
+		m.vol.Close()
+	}()
+	return m, nil
+}
+
+//Unmount stop serving the volume
+func (m *Mount) Unmount() error {
+	return m.server.Unmount()
+}
+
+//Stats return the counters of the mount
+func (m *Mount) Stats() Stats {
+	return Stats{
+		Ops:          atomic.LoadUint64(&m.stats.Ops),
+		Errors:       atomic.LoadUint64(&m.stats.Errors),
+		ReadBytes:    atomic.LoadUint64(&m.stats.ReadBytes),
+		WrittenBytes: atomic.LoadUint64(&m.stats.WrittenBytes),
+	}
+}
+
+//count account an operation and convert its error
+func (m *Mount) count(err error) syscall.Errno {
+	atomic.AddUint64(&m.stats.Ops, 1)
+	if err != nil {
+		atomic.AddUint64(&m.stats.Errors, 1)
+		return fs.ToErrno(err)
+	}
+	return 0
+}
+
+//node a file or directory of the volume, identified by its path
+type node struct {
+	fs.Inode
+	mount    *Mount
+	base     string
+	directIO bool
+}
+
+var (
+	_ fs.NodeGetattrer  = (*node)(nil)
+	_ fs.NodeSetattrer  = (*node)(nil)
+	_ fs.NodeLookuper   = (*node)(nil)
+	_ fs.NodeReaddirer  = (*node)(nil)
+	_ fs.NodeMkdirer    = (*node)(nil)
+	_ fs.NodeRmdirer    = (*node)(nil)
+	_ fs.NodeUnlinker   = (*node)(nil)
+	_ fs.NodeRenamer    = (*node)(nil)
+	_ fs.NodeCreater    = (*node)(nil)
+	_ fs.NodeOpener     = (*node)(nil)
+	_ fs.NodeSymlinker  = (*node)(nil)
+	_ fs.NodeReadlinker = (*node)(nil)
+	_ fs.NodeStatfser   = (*node)(nil)
+)
+
+//path return the path of the node on the volume
+func (n *node) path() string {
+	return path.Join(n.base, n.Path(nil))
+}
+
+func (n *node) child(name string) string {
+	return path.Join(n.path(), name)
+}
+
+//newChild register the inode of a child and fill out with its attributes
+func (n *node) newChild(ctx context.Context, st *syscall.Stat_t, out *fuse.EntryOut) *fs.Inode {
+	out.Attr.FromStat(st)
+	c := &node{mount: n.mount, base: n.base, directIO: n.directIO}
+	return n.NewInode(ctx, c, fs.StableAttr{Mode: st.Mode & syscall.S_IFMT, Ino: st.Ino})
+}
+
+//chownCaller give the entry created on p to the caller, as it is created by the daemon as root (group is inherited from a setgid parent like on a local filesystem)
+func (n *node) chownCaller(ctx context.Context, p string) error {
+	caller, ok := fuse.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	gid := int(caller.Gid)
+	st, err := n.mount.vol.Lstat(n.path())
+	if err != nil {
+		return err
+	}
+	if st.Mode&syscall.S_ISGID != 0 {
+		gid = -1
+	}
+	return n.mount.vol.Lchown(p, int(caller.Uid), gid)
+}
+
+func (n *node) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	st, err := n.mount.vol.Lstat(n.path())
+	if errno := n.mount.count(err); errno != 0 {
+		return errno
+	}
+	out.Attr.FromStat(st)
+	return 0
+}
+
+func (n *node) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	p := n.path()
+	vol := n.mount.vol
+	if mode, ok := in.GetMode(); ok {
+		if errno := n.mount.count(vol.Chmod(p, mode&07777)); errno != 0 {
+			return errno
+		}
+	}
+	uid, uok := in.GetUID()
+	gid, gok := in.GetGID()
+	if uok || gok {
+		u, g := -1, -1
+		if uok {
+			u = int(uid)
+		}
+		if gok {
+			g = int(gid)
+		}
+		if errno := n.mount.count(vol.Lchown(p, u, g)); errno != 0 {
+			return errno
+		}
+	}
+	if size, ok := in.GetSize(); ok {
+		var err error
+		if fh, ok := f.(*file); ok {
+			err = fh.f.Truncate(int64(size))
+		} else {
+			err = vol.Truncate(p, int64(size))
+		}
+		if errno := n.mount.count(err); errno != 0 {
+			return errno
+		}
+	}
+	atime, aok := in.GetATime()
+	mtime, mok := in.GetMTime()
+	if aok || mok {
+		var a, m *time.Time
+		if aok {
+			a = &atime
+		}
+		if mok {
+			m = &mtime
+		}
+		if errno := n.mount.count(vol.Utimens(p, a, m)); errno != 0 {
+			return errno
+		}
+	}
+	return n.Getattr(ctx, f, out)
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	st, err := n.mount.vol.Lstat(n.child(name))
+	if errno := n.mount.count(err); errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, st, out), 0
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	entries, err := n.mount.vol.ReadDir(n.path())
+	if errno := n.mount.count(err); errno != 0 {
+		return nil, errno
+	}
+	list := make([]fuse.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, fuse.DirEntry{Name: e.Name, Ino: e.Ino, Mode: e.Mode})
+	}
+	return fs.NewListDirStream(list), 0
+}
+
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	p := n.child(name)
+	if errno := n.mount.count(n.mount.vol.Mkdir(p, mode)); errno != 0 {
+		return nil, errno
+	}
+	if errno := n.mount.count(n.chownCaller(ctx, p)); errno != 0 {
+		n.mount.vol.Rmdir(p)
+		return nil, errno
+	}
+	st, err := n.mount.vol.Lstat(p)
+	if errno := n.mount.count(err); errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, st, out), 0
+}
+
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	return n.mount.count(n.mount.vol.Rmdir(n.child(name)))
+}
+
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	return n.mount.count(n.mount.vol.Unlink(n.child(name)))
+}
+
+func (n *node) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if flags != 0 {
+		return syscall.ENOTSUP //RENAME_EXCHANGE and RENAME_NOREPLACE are not available in libgfapi
+	}
+	p, ok := newParent.(*node)
+	if !ok {
+		return syscall.EXDEV
+	}
+	return n.mount.count(n.mount.vol.Rename(n.child(name), p.child(newName)))
+}
+
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	p := n.child(name)
+	f, err := n.mount.vol.Create(p, int(flags), mode)
+	if errno := n.mount.count(err); errno != 0 {
+		return nil, nil, 0, errno
+	}
+	if errno := n.mount.count(n.chownCaller(ctx, p)); errno != 0 {
+		f.Close()
+		n.mount.vol.Unlink(p)
+		return nil, nil, 0, errno
+	}
+	st, err := n.mount.vol.Lstat(p)
+	if errno := n.mount.count(err); errno != 0 {
+		f.Close()
+		return nil, nil, 0, errno
+	}
+	return n.newChild(ctx, st, out), &file{f: f, mount: n.mount}, n.openFlags(), 0
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	f, err := n.mount.vol.OpenFile(n.path(), int(flags))
+	if errno := n.mount.count(err); errno != 0 {
+		return nil, 0, errno
+	}
+	return &file{f: f, mount: n.mount}, n.openFlags(), 0
+}
+
+//openFlags bypass the kernel page cache if direct-io-mode is enabled
+func (n *node) openFlags() uint32 {
+	if n.directIO {
+		return fuse.FOPEN_DIRECT_IO
+	}
+	return 0
+}
+
+func (n *node) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	p := n.child(name)
+	if errno := n.mount.count(n.mount.vol.Symlink(target, p)); errno != 0 {
+		return nil, errno
+	}
+	if errno := n.mount.count(n.chownCaller(ctx, p)); errno != 0 {
+		n.mount.vol.Unlink(p)
+		return nil, errno
+	}
+	st, err := n.mount.vol.Lstat(p)
+	if errno := n.mount.count(err); errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, st, out), 0
+}
+
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	target, err := n.mount.vol.Readlink(n.path())
+	return target, n.mount.count(err)
+}
+
+func (n *node) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	st, err := n.mount.vol.Statvfs(n.path())
+	if errno := n.mount.count(err); errno != 0 {
+		return errno
+	}
+	out.Blocks, out.Bfree, out.Bavail = st.Blocks, st.Bfree, st.Bavail
+	out.Files, out.Ffree = st.Files, st.Ffree
+	out.Bsize, out.Frsize, out.NameLen = uint32(st.Bsize), uint32(st.Frsize), uint32(st.NameMax)
+	return 0
+}
+
+//file an opened file handle
+type file struct {
+	f     *File
+	mount *Mount
+}
+
+var (
+	_ fs.FileReader   = (*file)(nil)
+	_ fs.FileWriter   = (*file)(nil)
+	_ fs.FileFsyncer  = (*file)(nil)
+	_ fs.FileReleaser = (*file)(nil)
+)
+
+func (f *file) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	n, err := f.f.ReadAt(dest, off)
+	if errno := f.mount.count(err); errno != 0 {
+		return nil, errno
+	}
+	atomic.AddUint64(&f.mount.stats.ReadBytes, uint64(n))
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+func (f *file) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	n, err := f.f.WriteAt(data, off)
+	if errno := f.mount.count(err); errno != 0 {
+		return 0, errno
+	}
+	atomic.AddUint64(&f.mount.stats.WrittenBytes, uint64(n))
+	return uint32(n), 0
+}
+
+func (f *file) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	return f.mount.count(f.f.Sync())
+}
+
+func (f *file) Release(ctx context.Context) syscall.Errno {
+	return f.mount.count(f.f.Close())
+}
@@ -0,0 +1,357 @@
+//go:build gfapi
+// +build gfapi
+
+package gfapi
+
+// #cgo pkg-config: glusterfs-api
+// #include <stdlib.h>
+// #include <sys/stat.h>
+// #include <sys/statvfs.h>
+// #include <dirent.h>
+// #include <glusterfs/api/glfs.h>
+import "C"
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+//utimeOmit leave the time unchanged in Utimens (UTIME_OMIT)
+const utimeOmit = (1 << 30) - 2
+
+//Server a volfile server (Host is the path of the socket for unix transport)
+type Server struct {
+	Transport string
+	Host      string
+	Port      int
+}
+
+//Volume a gluster volume opened through libgfapi
+type Volume struct {
+	fs *C.glfs_t
+}
+
+//File an opened file of a volume
+type File struct {
+	fd *C.glfs_fd_t
+}
+
+//Dirent an entry of a directory
+type Dirent struct {
+	Name string
+	Ino  uint64
+	Mode uint32 //Only the type bits (S_IFDIR, ...)
+}
+
+//Statvfs usage of a volume
+type Statvfs struct {
+	Bsize, Frsize, NameMax uint64
+	Blocks, Bfree, Bavail  uint64
+	Files, Ffree           uint64
+}
+
+//Open connect to the volume through the first reachable server, xlators are tuned with xlatorOptions (xlator -> key -> value) before init
+func Open(volume string, servers []Server, logLevel int, xlatorOptions map[string]map[string]string) (*Volume, error) {
+	cvol := C.CString(volume)
+	defer C.free(unsafe.Pointer(cvol))
+	fs, err := C.glfs_new(cvol)
+	if fs == nil {
+		return nil, fmt.Errorf("glfs_new %s: %v", volume, err)
+	}
+	v := &Volume{fs: fs}
+	for _, s := range servers {
+		ct, ch := C.CString(s.Transport), C.CString(s.Host)
+		ret, err := C.glfs_set_volfile_server(fs, ct, ch, C.int(s.Port))
+		C.free(unsafe.Pointer(ct))
+		C.free(unsafe.Pointer(ch))
+		if ret != 0 {
+			v.Close()
+			return nil, fmt.Errorf("glfs_set_volfile_server %s: %v", s.Host, err)
+		}
+	}
+	clog := C.CString("/dev/stderr")
+	defer C.free(unsafe.Pointer(clog))
+	C.glfs_set_logging(fs, clog, C.int(logLevel))
+	for xlator, opts := range xlatorOptions {
+		for key, val := range opts {
+			cx, ck, cv := C.CString(xlator), C.CString(key), C.CString(val)
+			ret, err := C.glfs_set_xlator_option(fs, cx, ck, cv)
+			C.free(unsafe.Pointer(cx))
+			C.free(unsafe.Pointer(ck))
+			C.free(unsafe.Pointer(cv))
+			if ret != 0 {
+				v.Close()
+				return nil, fmt.Errorf("glfs_set_xlator_option %s.%s: %v", xlator, key, err)
+			}
+		}
+	}
+	if ret, err := C.glfs_init(fs); ret != 0 {
+		v.Close()
+		return nil, fmt.Errorf("glfs_init %s: %v", volume, err)
+	}
+	return v, nil
+}
+
+//Close disconnect from the volume
+func (v *Volume) Close() error {
+	if ret, err := C.glfs_fini(v.fs); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Lstat return the attributes of path without following symlinks
+func (v *Volume) Lstat(path string) (*syscall.Stat_t, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	var st syscall.Stat_t
+	if ret, err := C.glfs_lstat(v.fs, cpath, (*C.struct_stat)(unsafe.Pointer(&st))); ret != 0 {
+		return nil, err
+	}
+	return &st, nil
+}
+
+//Statvfs return the usage of the volume
+func (v *Volume) Statvfs(path string) (*Statvfs, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	var st C.struct_statvfs
+	if ret, err := C.glfs_statvfs(v.fs, cpath, &st); ret != 0 {
+		return nil, err
+	}
+	return &Statvfs{
+		Bsize: uint64(st.f_bsize), Frsize: uint64(st.f_frsize), NameMax: uint64(st.f_namemax),
+		Blocks: uint64(st.f_blocks), Bfree: uint64(st.f_bfree), Bavail: uint64(st.f_bavail),
+		Files: uint64(st.f_files), Ffree: uint64(st.f_ffree),
+	}, nil
+}
+
+//ReadDir return the entries of a directory except . and ..
+func (v *Volume) ReadDir(path string) ([]Dirent, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	fd, err := C.glfs_opendir(v.fs, cpath)
+	if fd == nil {
+		return nil, err
+	}
+	defer C.glfs_closedir(fd)
+	var entries []Dirent
+	for {
+		d := C.glfs_readdir(fd)
+		if d == nil {
+			return entries, nil
+		}
+		name := C.GoString(&d.d_name[0])
+		if name == "." || name == ".." {
+			continue
+		}
+		entries = append(entries, Dirent{Name: name, Ino: uint64(d.d_ino), Mode: uint32(d.d_type) << 12})
+	}
+}
+
+//Mkdir create a directory
+func (v *Volume) Mkdir(path string, mode uint32) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.glfs_mkdir(v.fs, cpath, C.mode_t(mode)); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//MkdirAll create a directory and its parents if they don't exist
+func (v *Volume) MkdirAll(path string, mode uint32) error {
+	if st, err := v.Lstat(path); err == nil {
+		if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+			return syscall.ENOTDIR
+		}
+		return nil
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i] == '/' {
+			if err := v.MkdirAll(path[:i], mode); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if err := v.Mkdir(path, mode); err != nil && err != syscall.EEXIST {
+		return err
+	}
+	return nil
+}
+
+//Rmdir remove an empty directory
+func (v *Volume) Rmdir(path string) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.glfs_rmdir(v.fs, cpath); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Unlink remove a file
+func (v *Volume) Unlink(path string) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.glfs_unlink(v.fs, cpath); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Rename move oldpath to newpath
+func (v *Volume) Rename(oldpath, newpath string) error {
+	cold, cnew := C.CString(oldpath), C.CString(newpath)
+	defer C.free(unsafe.Pointer(cold))
+	defer C.free(unsafe.Pointer(cnew))
+	if ret, err := C.glfs_rename(v.fs, cold, cnew); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Symlink create path as a symlink to target
+func (v *Volume) Symlink(target, path string) error {
+	ctarget, cpath := C.CString(target), C.CString(path)
+	defer C.free(unsafe.Pointer(ctarget))
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.glfs_symlink(v.fs, ctarget, cpath); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Readlink return the target of a symlink
+func (v *Volume) Readlink(path string) ([]byte, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	buf := make([]byte, syscall.PathMax)
+	n, err := C.glfs_readlink(v.fs, cpath, (*C.char)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
+	if n < 0 {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+//Chmod change the permissions of path
+func (v *Volume) Chmod(path string, mode uint32) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.glfs_chmod(v.fs, cpath, C.mode_t(mode)); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Lchown change the owner of path without following symlinks (-1 keep the current value)
+func (v *Volume) Lchown(path string, uid, gid int) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.glfs_lchown(v.fs, cpath, C.uid_t(uid), C.gid_t(gid)); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Utimens change access and modification times of path, nil keep the current value
+func (v *Volume) Utimens(path string, atime, mtime *time.Time) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	var ts [2]C.struct_timespec
+	for i, t := range []*time.Time{atime, mtime} {
+		if t == nil {
+			ts[i].tv_nsec = utimeOmit
+			continue
+		}
+		ts[i].tv_sec = C.time_t(t.Unix())
+		ts[i].tv_nsec = C.long(t.Nanosecond())
+	}
+	if ret, err := C.glfs_lutimens(v.fs, cpath, &ts[0]); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Truncate change the size of path
+func (v *Volume) Truncate(path string, size int64) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.glfs_truncate(v.fs, cpath, C.off_t(size)); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//OpenFile open an existing file
+func (v *Volume) OpenFile(path string, flags int) (*File, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	fd, err := C.glfs_open(v.fs, cpath, C.int(flags))
+	if fd == nil {
+		return nil, err
+	}
+	return &File{fd: fd}, nil
+}
+
+//Create create and open a file
+func (v *Volume) Create(path string, flags int, mode uint32) (*File, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	fd, err := C.glfs_creat(v.fs, cpath, C.int(flags), C.mode_t(mode))
+	if fd == nil {
+		return nil, err
+	}
+	return &File{fd: fd}, nil
+}
+
+//ReadAt read len(b) bytes at off, less are returned at end of file
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := C.glfs_pread(f.fd, unsafe.Pointer(&b[0]), C.size_t(len(b)), C.off_t(off), 0, nil)
+	if n < 0 {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+//WriteAt write b at off
+func (f *File) WriteAt(b []byte, off int64) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := C.glfs_pwrite(f.fd, unsafe.Pointer(&b[0]), C.size_t(len(b)), C.off_t(off), 0, nil, nil)
+	if n < 0 {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+//Truncate change the size of the file
+func (f *File) Truncate(size int64) error {
+	if ret, err := C.glfs_ftruncate(f.fd, C.off_t(size), nil, nil); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Sync flush the file to the bricks
+func (f *File) Sync() error {
+	if ret, err := C.glfs_fsync(f.fd, nil, nil); ret != 0 {
+		return err
+	}
+	return nil
+}
+
+//Close close the file
+func (f *File) Close() error {
+	if ret, err := C.glfs_close(f.fd); ret != 0 {
+		return err
+	}
+	return nil
+}
@@ -2,11 +2,18 @@ package gluster
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/go-plugins-helpers/volume"
+	"github.com/sapk/docker-volume-gluster/gluster/admin"
+	"github.com/sapk/docker-volume-gluster/gluster/csi"
 	"github.com/sapk/docker-volume-gluster/gluster/driver"
 	"github.com/spf13/cobra"
 )
@@ -16,8 +23,44 @@ const (
 	VerboseFlag = "verbose"
 	//MountUniqNameFlag flag to set mount point based on definition and not name of volume to not have multile mount of same distant volume
 	MountUniqNameFlag = "mount-uniq"
+	//OfflineFlag flag allowing admin commands to change the persistence file when the daemon is not reachable
+	OfflineFlag = "offline"
 	//BasedirFlag flag to set the basedir of mounted volumes
 	BasedirFlag = "basedir"
+	//SSLCAFlag flag to set the default CA used to connect to gluster
+	SSLCAFlag = "ssl-ca"
+	//SSLCertFlag flag to set the default certificate used to connect to gluster
+	SSLCertFlag = "ssl-cert"
+	//SSLKeyFlag flag to set the default private key used to connect to gluster
+	SSLKeyFlag = "ssl-key"
+	//SSLFlag flag to enable I/O encryption by default
+	SSLFlag = "ssl"
+	//SSLMgmtFlag flag to enable management encryption by default
+	SSLMgmtFlag = "ssl-mgmt"
+	//ClustersFlag flag to set the file defining named group of servers
+	ClustersFlag = "clusters"
+	//AutoCreateFlag flag to set the voluri under which unknown volumes are created
+	AutoCreateFlag = "auto-create"
+	//AutoCreatePatternFlag flag to set the pattern that name of auto created volumes need to match
+	AutoCreatePatternFlag = "auto-create-pattern"
+	//RegistryFlag flag to set the voluri of the gluster volume sharing volume definitions
+	RegistryFlag = "registry"
+	//RegistryDirFlag flag to set the folder where volume definitions are shared
+	RegistryDirFlag = "registry-dir"
+	//KeystoreFlag flag to set the folder of the identities usable with the credentials option
+	KeystoreFlag = "keystore"
+	//BackendFlag flag to set the backend used by volumes that don't set one
+	BackendFlag = "backend"
+	//AdminSocketFlag flag to set the unix socket of the admin API
+	AdminSocketFlag = "admin-socket"
+	//AdminGIDFlag flag to set the group allowed to use the admin socket
+	AdminGIDFlag = "admin-gid"
+	//CSIEndpointFlag flag to set the endpoint listening for CSI calls
+	CSIEndpointFlag = "endpoint"
+	//CSINodeIDFlag flag to set the id of the node reported to the CSI orchestrator
+	CSINodeIDFlag = "nodeid"
+	//CSINameFlag flag to set the name of the CSI driver
+	CSINameFlag = "csi-name"
 	longHelp    = `
 docker-volume-gluster (GlusterFS Volume Driver Plugin)
 Provides docker volume support for GlusterFS.
@@ -37,20 +80,34 @@ var (
 	//PluginAlias plugin alias name in docker
 	PluginAlias = "gluster"
 	//BaseDir of mounted volumes
-	BaseDir       = ""
+	BaseDir = ""
+	//AdminSocket unix socket of the admin API (disabled if empty)
+	AdminSocket   = ""
+	adminGID      = -1
+	offline       = false
+	sslIO         = false
+	sslMgmt       = false
 	fuseOpts      = ""
 	mountUniqName = false
+	csiEndpoint   = ""
+	csiNodeID     = ""
+	csiName       = ""
 	rootCmd       = &cobra.Command{
 		Use:              "docker-volume-gluster",
 		Short:            "GlusterFS - Docker volume driver plugin",
 		Long:             longHelp,
-		PersistentPreRun: setupLogger,
+		PersistentPreRun: loadConfig,
 	}
 	daemonCmd = &cobra.Command{
 		Use:   "daemon",
 		Short: "Run listening volume drive deamon to listen for mount request",
 		Run:   DaemonStart,
 	}
+	csiCmd = &cobra.Command{
+		Use:   "csi",
+		Short: "Run CSI driver (Identity, Controller and Node services) for Kubernetes or Nomad",
+		Run:   CSIStart,
+	}
 	versionCmd = &cobra.Command{
 		Use:   "version",
 		Short: "Display current version and build date",
@@ -64,7 +121,7 @@ var (
 func Init() {
 	setupFlags()
 	rootCmd.Long = fmt.Sprintf(longHelp, Version, Branch, Commit, BuildTime)
-	rootCmd.AddCommand(versionCmd, daemonCmd)
+	rootCmd.AddCommand(versionCmd, daemonCmd, csiCmd, volumeCmd, adminCmd, exportCmd, importCmd, recoverCmd, gcCmd, configCmd)
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
@@ -72,21 +129,125 @@ func Init() {
 
 //DaemonStart Start the deamon
 func DaemonStart(cmd *cobra.Command, args []string) {
+	if err := driver.CheckBackend(driver.DefaultBackend); err != nil {
+		log.Fatal(err)
+	}
 	d := driver.Init(BaseDir, mountUniqName)
 	log.Debug(d)
-	h := volume.NewHandler(d)
+	if _, err := d.GC(false); err != nil {
+		log.Warnf("Unable to collect unused directories of %s: %v", BaseDir, err)
+	}
+	if restored, err := d.RestoreMounts(); err != nil {
+		log.Warnf("Unable to restore mountpoints in use: %v", err)
+	} else if len(restored) > 0 {
+		log.Infof("Mounted again %s", strings.Join(restored, ", "))
+	}
+	a := &daemonAdmin{GlusterDriver: d, cmd: cmd}
+	go a.reloadOnSIGHUP()
+	stop := make(chan struct{})
+	var listeners []net.Listener //Closed before draining so that admin calls don't race with shutdown
+	if AdminSocket != "" {
+		if l, err := admin.Listen(AdminSocket, adminGID); err != nil {
+			log.Errorf("Admin API not started: %v", err)
+		} else {
+			listeners = append(listeners, l)
+			go func() {
+				if err := admin.Serve(l, a); err != nil && !isClosed(stop) {
+					log.Errorf("Admin API stopped: %v", err)
+				}
+			}()
+		}
+	}
+	t := &trackedDriver{Driver: d}
+	h := volume.NewHandler(t)
 	log.Debug(h)
-	err := h.ServeUnix(PluginAlias, 0)
-	if err != nil {
-		log.Debug(err)
+	//SDK listener create the plugin folder and support systemd socket activation, it stay open at shutdown as trackedDriver refuse new requests
+	served := make(chan error, 1)
+	go func() {
+		served <- h.ServeUnix(PluginAlias, 0)
+	}()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	var serveErr error
+	select {
+	case s := <-sig:
+		log.Infof("Received %s, shutting down", s)
+	case serveErr = <-served:
+		log.Errorf("Plugin API stopped: %v", serveErr)
+	}
+	close(stop)
+	for _, l := range listeners {
+		l.Close()
+	}
+	if err := shutdown(t, d.Shutdown); err != nil {
+		log.Error(err)
+		serveErr = err
+	}
+	os.Remove(filepath.Join(pluginSockDir, PluginAlias+".sock"))
+	if AdminSocket != "" {
+		os.Remove(AdminSocket)
+	}
+	if serveErr != nil {
+		os.Exit(1)
+	}
+}
+
+//CSIStart Start the CSI driver
+func CSIStart(cmd *cobra.Command, args []string) {
+	//CSI keep no state of its own, it must not share (nor overwrite) the persistence of a daemon running on the same node
+	if err := csi.New(csiName, Version, csiNodeID, driver.NewMounter()).Serve(csiEndpoint); err != nil {
+		log.Fatal(err)
 	}
 }
 
 func setupFlags() {
+	setupVolumeCmd()
+	setupExportCmd()
+	setupRecoverCmd()
+	setupGCCmd()
+	setupConfigCmd()
+	setupShutdownCmd()
 	rootCmd.PersistentFlags().BoolP(VerboseFlag, "v", os.Getenv("DEBUG") == "1", "Turns on verbose logging")
+	rootCmd.PersistentFlags().StringVar(&AdminSocket, AdminSocketFlag, envOrDefault("ADMIN_SOCKET", "/run/docker-volume-gluster/admin.sock"), "Unix socket of the admin API of the daemon (empty to disable)")
 	rootCmd.PersistentFlags().StringVarP(&BaseDir, BasedirFlag, "b", filepath.Join(volume.DefaultDockerRootDirectory, PluginAlias), "Mounted volume base directory")
 
-	daemonCmd.Flags().BoolVar(&mountUniqName, MountUniqNameFlag, os.Getenv("MOUNT_UNIQ") == "1", "Set mountpoint based on definition and not the name of volume")
+	rootCmd.PersistentFlags().BoolVar(&mountUniqName, MountUniqNameFlag, os.Getenv("MOUNT_UNIQ") == "1", "Set mountpoint based on definition and not the name of volume")
+	rootCmd.PersistentFlags().BoolVar(&offline, OfflineFlag, false, "Allow admin commands to change the persistence file when the daemon is not reachable (the daemon need to be stopped)")
+	daemonCmd.Flags().StringVar(&driver.DefaultSSL.CA, SSLCAFlag, os.Getenv("SSL_CA"), "Default CA file used to connect to gluster with TLS")
+	daemonCmd.Flags().StringVar(&driver.DefaultSSL.Cert, SSLCertFlag, os.Getenv("SSL_CERT"), "Default certificate file used to connect to gluster with TLS")
+	daemonCmd.Flags().StringVar(&driver.DefaultSSL.Key, SSLKeyFlag, os.Getenv("SSL_KEY"), "Default private key file used to connect to gluster with TLS")
+	daemonCmd.Flags().BoolVar(&sslIO, SSLFlag, os.Getenv("SSL") == "1", "Enable I/O encryption by default")
+	daemonCmd.Flags().BoolVar(&sslMgmt, SSLMgmtFlag, os.Getenv("SSL_MGMT") == "1", "Enable management encryption by default")
+	driver.DefaultSSL.IO, driver.DefaultSSL.Mgmt = &sslIO, &sslMgmt
+	daemonCmd.Flags().StringVar(&driver.ClustersFile, ClustersFlag, envOrDefault("CLUSTERS_FILE", driver.ClustersFile), "File defining named group of servers usable in voluri as @name")
+	daemonCmd.Flags().StringVar(&driver.AutoCreateVolURI, AutoCreateFlag, os.Getenv("AUTO_CREATE"), "Create unknown volumes as a subdir named after the volume of this voluri")
+	daemonCmd.Flags().StringVar(&driver.AutoCreatePattern, AutoCreatePatternFlag, envOrDefault("AUTO_CREATE_PATTERN", driver.AutoCreatePattern), "Regexp that name of unknown volumes need to match to be auto created")
+	daemonCmd.Flags().StringVar(&driver.RegistryVolURI, RegistryFlag, os.Getenv("REGISTRY"), "Voluri of the gluster volume used to share volume definitions between hosts")
+	daemonCmd.Flags().BoolVar(&driver.RecoveryMode, RecoveryFlag, os.Getenv("RECOVERY") == "1", "Accept Create of volumes whose mountpoint already exist (replayed by docker after loss of persistence file)")
+	daemonCmd.Flags().IntVar(&adminGID, AdminGIDFlag, envIntOrDefault("ADMIN_GID", -1), "Group allowed to use the admin socket (only root if -1)")
+	daemonCmd.Flags().StringVar(&driver.RegistryDir, RegistryDirFlag, os.Getenv("REGISTRY_DIR"), "Folder where volume definitions are shared (registry volume is mounted on it)")
+	daemonCmd.Flags().StringVar(&driver.KeystoreDir, KeystoreFlag, envOrDefault("KEYSTORE", driver.KeystoreDir), "Folder of the identities usable with the credentials option (one subfolder with glusterfs.ca, glusterfs.pem and glusterfs.key by identity)")
+	daemonCmd.Flags().StringVar(&driver.DefaultBackend, BackendFlag, envOrDefault("BACKEND", driver.DefaultBackend), fmt.Sprintf("Backend used by volumes that don't set one (available: %s)", strings.Join(driver.BackendNames(), ", ")))
+
+	hostname, _ := os.Hostname()
+	csiCmd.Flags().StringVar(&csiEndpoint, CSIEndpointFlag, envOrDefault("CSI_ENDPOINT", "unix:///csi/csi.sock"), "Endpoint listening for CSI calls (unix:///path or tcp://host:port)")
+	csiCmd.Flags().StringVar(&csiNodeID, CSINodeIDFlag, envOrDefault("NODE_ID", hostname), "Id of the node reported to the orchestrator")
+	csiCmd.Flags().StringVar(&csiName, CSINameFlag, envOrDefault("CSI_NAME", "gluster.sapk.fr"), "Name of the CSI driver")
+	csiCmd.Flags().StringVar(&driver.ClustersFile, ClustersFlag, envOrDefault("CLUSTERS_FILE", driver.ClustersFile), "File defining named group of servers usable in voluri as @name")
+}
+
+func envOrDefault(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+func envIntOrDefault(key string, def int) int {
+	if val, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return val
+	}
+	return def
 }
 
 func setupLogger(cmd *cobra.Command, args []string) {
@@ -0,0 +1,59 @@
+package gluster
+
+import (
+	"fmt"
+
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/cobra"
+)
+
+const (
+	//RecoveryFlag flag to accept Create of volumes whose mountpoint already exist
+	RecoveryFlag = "recovery"
+	//DockerSocketFlag flag to set the unix socket of the docker engine API
+	DockerSocketFlag = "docker-socket"
+	//DriverFlag flag to set the driver names of our volumes in docker
+	DriverFlag = "driver"
+)
+
+var (
+	recoverDrivers = []string{PluginAlias, "sapk/plugin-gluster:latest"}
+	recoverCmd     = &cobra.Command{
+		Use:   "recover",
+		Short: "Rebuild volume definitions from the volumes known by docker (after loss of persistence file)",
+		Args:  cobra.NoArgs,
+		RunE:  recoverVolumes,
+	}
+)
+
+func setupRecoverCmd() {
+	recoverCmd.Flags().StringVar(&driver.DockerSocket, DockerSocketFlag, envOrDefault("DOCKER_SOCKET", driver.DockerSocket), "Unix socket of the docker engine API")
+	recoverCmd.Flags().StringSliceVar(&recoverDrivers, DriverFlag, recoverDrivers, "Driver names of the volumes to recover")
+	recoverCmd.Flags().BoolVar(&importDryRun, DryRunFlag, false, "Only show what would be done")
+	recoverCmd.Flags().StringVar(&outputFormat, FormatFlag, outputFormat, "Output format (table or json)")
+}
+
+func recoverVolumes(cmd *cobra.Command, args []string) error {
+	e, err := driver.DockerVolumes(recoverDrivers)
+	if err != nil {
+		return err
+	}
+	driver.RecoveryMode = true //Only used if the daemon is not running
+	d, err := adminDriver(!importDryRun)
+	if err != nil {
+		return err
+	}
+	results, err := d.Import(e, driver.ConflictSkip, importDryRun)
+	if err != nil {
+		return err
+	}
+	if err := printImport(cmd.OutOrStdout(), results); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Error != "" {
+			return fmt.Errorf("some volumes could not be recovered (is the daemon started with --%s ?)", RecoveryFlag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package gluster
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/cobra"
+)
+
+//daemonAdmin driver of the running daemon completed by the reload of its config file
+type daemonAdmin struct {
+	*driver.GlusterDriver
+	cmd *cobra.Command
+}
+
+//ReloadConfig read again the config file, apply the settings that can change at runtime and return the ones needing a restart
+func (a *daemonAdmin) ReloadConfig() ([]string, error) {
+	c, err := readConfig(a.cmd)
+	if err != nil {
+		return nil, err
+	}
+	restart := []string{}
+	if c.BaseDir != "" && c.BaseDir != BaseDir && notOverridden(a.cmd, BasedirFlag, "") {
+		restart = append(restart, "basedir")
+	}
+	if c.StateDir != "" && filepath.Clean(c.StateDir) != filepath.Clean(driver.CfgFolder) {
+		restart = append(restart, "statedir")
+	}
+	if c.MountUniq != nil && *c.MountUniq != mountUniqName && notOverridden(a.cmd, MountUniqNameFlag, "MOUNT_UNIQ") {
+		restart = append(restart, "mount-uniq")
+	}
+
+	a.GetLock().Lock()
+	applyRuntimeConfig(a.cmd, c)
+	a.GetLock().Unlock()
+
+	for _, s := range restart {
+		log.Warnf("Setting %s changed in %s, a restart is needed to apply it", s, ConfigFile)
+	}
+	log.Infof("Config reloaded from %s", ConfigFile)
+	return restart, nil
+}
+
+//reloadOnSIGHUP reload the config file each time the daemon receive SIGHUP
+func (a *daemonAdmin) reloadOnSIGHUP() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		if _, err := a.ReloadConfig(); err != nil {
+			log.Errorf("Unable to reload config, keeping current one: %v", err)
+		}
+	}
+}
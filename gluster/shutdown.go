@@ -0,0 +1,151 @@
+package gluster
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+const (
+	//UnmountIdleFlag flag to unmount mountpoints not used by any container at shutdown
+	UnmountIdleFlag = "unmount-idle"
+	//ShutdownTimeoutFlag flag to set how long to wait for in-flight requests at shutdown
+	ShutdownTimeoutFlag = "shutdown-timeout"
+)
+
+//pluginSockDir folder where docker look for plugin sockets
+const pluginSockDir = "/run/docker/plugins"
+
+var (
+	unmountIdle     = false
+	shutdownTimeout = 30 * time.Second
+)
+
+func setupShutdownCmd() {
+	daemonCmd.Flags().BoolVar(&unmountIdle, UnmountIdleFlag, os.Getenv("UNMOUNT_IDLE") == "1", "Unmount at shutdown the mountpoints not used by any container")
+	daemonCmd.Flags().DurationVar(&shutdownTimeout, ShutdownTimeoutFlag, envDurationOrDefault("SHUTDOWN_TIMEOUT", shutdownTimeout), "Time to wait for in-flight requests at shutdown")
+}
+
+//trackedDriver volume driver waiting for in-flight requests and refusing new ones once shutdown is started
+type trackedDriver struct {
+	volume.Driver
+	lock     sync.Mutex
+	inflight sync.WaitGroup
+	closed   bool
+}
+
+func (t *trackedDriver) enter() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return fmt.Errorf("daemon is shutting down")
+	}
+	t.inflight.Add(1)
+	return nil
+}
+
+//drain refuse new requests and wait for in-flight ones, return false on timeout
+func (t *trackedDriver) drain(timeout time.Duration) bool {
+	t.lock.Lock()
+	t.closed = true
+	t.lock.Unlock()
+	done := make(chan struct{})
+	go func() {
+		t.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *trackedDriver) Create(r *volume.CreateRequest) error {
+	if err := t.enter(); err != nil {
+		return err
+	}
+	defer t.inflight.Done()
+	return t.Driver.Create(r)
+}
+
+func (t *trackedDriver) List() (*volume.ListResponse, error) {
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	defer t.inflight.Done()
+	return t.Driver.List()
+}
+
+func (t *trackedDriver) Get(r *volume.GetRequest) (*volume.GetResponse, error) {
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	defer t.inflight.Done()
+	return t.Driver.Get(r)
+}
+
+func (t *trackedDriver) Remove(r *volume.RemoveRequest) error {
+	if err := t.enter(); err != nil {
+		return err
+	}
+	defer t.inflight.Done()
+	return t.Driver.Remove(r)
+}
+
+func (t *trackedDriver) Path(r *volume.PathRequest) (*volume.PathResponse, error) {
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	defer t.inflight.Done()
+	return t.Driver.Path(r)
+}
+
+func (t *trackedDriver) Mount(r *volume.MountRequest) (*volume.MountResponse, error) {
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	defer t.inflight.Done()
+	return t.Driver.Mount(r)
+}
+
+func (t *trackedDriver) Unmount(r *volume.UnmountRequest) error {
+	if err := t.enter(); err != nil {
+		return err
+	}
+	defer t.inflight.Done()
+	return t.Driver.Unmount(r)
+}
+
+//isClosed return true once shutdown has closed stop
+func isClosed(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+//closing return true once new requests are refused
+func (t *trackedDriver) closing() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.closed
+}
+
+//shutdown stop the daemon: wait for in-flight requests, flush state and optionally unmount idle mountpoints
+func shutdown(t *trackedDriver, flush func(unmountIdle bool) error) error {
+	if !t.drain(shutdownTimeout) {
+		log.Warnf("Some requests are still running after %s, stopping anyway", shutdownTimeout)
+	}
+	if err := flush(unmountIdle); err != nil {
+		return fmt.Errorf("unable to save state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package gluster
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+type slowDriver struct {
+	volume.Driver
+	t       *testing.T
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowDriver) Mount(r *volume.MountRequest) (*volume.MountResponse, error) {
+	first := false
+	s.once.Do(func() {
+		first = true
+		close(s.started)
+	})
+	if !first {
+		s.t.Errorf("Expected %s to not reach the driver", r.Name)
+		return nil, fmt.Errorf("unexpected mount")
+	}
+	<-s.release
+	return &volume.MountResponse{Mountpoint: "/mnt/" + r.Name}, nil
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	s := &slowDriver{t: t, started: make(chan struct{}), release: make(chan struct{})}
+	td := &trackedDriver{Driver: s}
+
+	mounted := make(chan error)
+	go func() {
+		_, err := td.Mount(&volume.MountRequest{Name: "test"})
+		mounted <- err
+	}()
+	<-s.started
+
+	flushed := false
+	done := make(chan error)
+	go func() {
+		done <- shutdown(td, func(unmountIdle bool) error {
+			flushed = true
+			return nil
+		})
+	}()
+	for deadline := time.Now().Add(5 * time.Second); !td.closing(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected shutdown to refuse new requests")
+		}
+	}
+	if _, err := td.Mount(&volume.MountRequest{Name: "other"}); err == nil {
+		t.Error("Expected new requests to be refused during shutdown")
+	}
+	select {
+	case <-done:
+		t.Fatal("Expected shutdown to wait for in-flight request")
+	default:
+	}
+	close(s.release)
+	if err := <-mounted; err != nil {
+		t.Errorf("Expected in-flight request to complete, got %v", err)
+	}
+	if err := <-done; err != nil || !flushed {
+		t.Errorf("Expected state to be flushed, got %v", err)
+	}
+
+	if err := shutdown(&trackedDriver{Driver: s}, func(bool) error { return fmt.Errorf("disk full") }); err == nil {
+		t.Error("Expected flush error to be returned")
+	}
+}
@@ -0,0 +1,277 @@
+package gluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/sapk/docker-volume-gluster/gluster/admin"
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+	"github.com/spf13/cobra"
+)
+
+const (
+	//FormatFlag flag to set the output format of admin commands
+	FormatFlag = "format"
+	//ForceFlag flag to remove a volume whose connections are stale
+	ForceFlag = "force"
+)
+
+var (
+	outputFormat = "table"
+	forceRemove  = false
+	volumeCmd    = &cobra.Command{
+		Use:   "volume",
+		Short: "Manage volumes known by the driver (through the running daemon or from the persistence file)",
+	}
+	volumeLsCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "List volumes with their mount status",
+		Args:  cobra.NoArgs,
+		RunE:  volumeLs,
+	}
+	volumeInspectCmd = &cobra.Command{
+		Use:   "inspect VOLUME [VOLUME...]",
+		Short: "Display detailed information on volumes",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  volumeInspect,
+	}
+	volumeRmCmd = &cobra.Command{
+		Use:   "rm VOLUME [VOLUME...]",
+		Short: "Remove volumes not used by a container",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  volumeRm,
+	}
+	volumePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove all volumes not used by a container nor mounted",
+		Args:  cobra.NoArgs,
+		RunE:  volumePrune,
+	}
+	volumeUnmountCmd = &cobra.Command{
+		Use:   "unmount VOLUME",
+		Short: "Force unmount of a volume even if used by containers (need running daemon)",
+		Args:  cobra.ExactArgs(1),
+		RunE:  volumeUnmount,
+	}
+	volumeRemountCmd = &cobra.Command{
+		Use:   "remount VOLUME",
+		Short: "Unmount and mount again a volume (need running daemon)",
+		Args:  cobra.ExactArgs(1),
+		RunE:  volumeRemount,
+	}
+	adminCmd = &cobra.Command{
+		Use:   "admin",
+		Short: "Act on the running daemon through its admin socket",
+	}
+	adminReloadCmd = &cobra.Command{
+		Use:   "reload",
+		Short: "Make the daemon read again its persistence file",
+		Args:  cobra.NoArgs,
+		RunE:  adminReload,
+	}
+	adminReconcileCmd = &cobra.Command{
+		Use:   "reconcile",
+		Short: "Mount used volumes that are not mounted and unmount unused ones",
+		Args:  cobra.NoArgs,
+		RunE:  adminReconcile,
+	}
+	adminReloadConfigCmd = &cobra.Command{
+		Use:   "reload-config",
+		Short: "Make the daemon read again its config file (same as SIGHUP)",
+		Args:  cobra.NoArgs,
+		RunE:  adminReloadConfig,
+	}
+	adminGoroutinesCmd = &cobra.Command{
+		Use:   "goroutines",
+		Short: "Dump stack of all goroutines of the daemon",
+		Args:  cobra.NoArgs,
+		RunE:  adminGoroutines,
+	}
+)
+
+//volumeAdmin operations available on volumes through the daemon or the persistence file
+type volumeAdmin interface {
+	Volumes() ([]driver.VolumeInfo, error)
+	Volume(name string) (*driver.VolumeInfo, error)
+	RemoveVolume(name string, force bool) error
+	Prune() ([]string, error)
+	Export() (*driver.Export, error)
+	Import(e *driver.Export, conflict string, dryRun bool) ([]driver.ImportResult, error)
+	GC(dryRun bool) (*driver.GCResult, error)
+}
+
+func setupVolumeCmd() {
+	volumeCmd.PersistentFlags().StringVar(&outputFormat, FormatFlag, outputFormat, "Output format (table or json)")
+	volumeRmCmd.Flags().BoolVarP(&forceRemove, ForceFlag, "f", false, "Reset connections of volumes that are not mounted anymore before removing them")
+	volumeCmd.AddCommand(volumeLsCmd, volumeInspectCmd, volumeRmCmd, volumePruneCmd, volumeUnmountCmd, volumeRemountCmd)
+	adminCmd.AddCommand(adminReloadCmd, adminReloadConfigCmd, adminReconcileCmd, adminGoroutinesCmd)
+}
+
+//adminDriver return the running daemon if reachable or else the driver state loaded from the persistence file with the daemon config.
+//As a running daemon would overwrite the persistence file, commands changing it need --offline when the daemon is not reachable
+func adminDriver(mutating bool) (volumeAdmin, error) {
+	c, err := adminClient()
+	if err == nil {
+		return c, nil
+	}
+	if mutating && !offline {
+		return nil, fmt.Errorf("%v, use --%s to change the persistence file directly (only when the daemon is stopped)", err, OfflineFlag)
+	}
+	if log.GetLevel() != log.DebugLevel {
+		log.SetLevel(log.ErrorLevel) //Keep output clean for scripting
+	}
+	if !mutating {
+		return driver.Load(BaseDir, mountUniqName), nil //Inspection must not migrate or save the state
+	}
+	return driver.Init(BaseDir, mountUniqName), nil
+}
+
+//adminClient return a client of the running daemon
+func adminClient() (*admin.Client, error) {
+	c := admin.NewClient(AdminSocket)
+	if err := c.Ping(); err != nil {
+		log.Debugf("Daemon not reachable on %s: %v", AdminSocket, err)
+		return nil, fmt.Errorf("daemon not reachable on %s: %v", AdminSocket, err)
+	}
+	return c, nil
+}
+
+func volumeLs(cmd *cobra.Command, args []string) error {
+	d, err := adminDriver(false)
+	if err != nil {
+		return err
+	}
+	infos, err := d.Volumes()
+	if err != nil {
+		return err
+	}
+	return printVolumes(cmd.OutOrStdout(), infos)
+}
+
+func volumeInspect(cmd *cobra.Command, args []string) error {
+	d, err := adminDriver(false)
+	if err != nil {
+		return err
+	}
+	infos := make([]driver.VolumeInfo, 0, len(args))
+	for _, name := range args {
+		info, err := d.Volume(name)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, *info)
+	}
+	if outputFormat == "table" {
+		outputFormat = "json" //Inspect is detailed by default
+	}
+	return printVolumes(cmd.OutOrStdout(), infos)
+}
+
+func volumeRm(cmd *cobra.Command, args []string) error {
+	d, err := adminDriver(true)
+	if err != nil {
+		return err
+	}
+	var failed bool
+	for _, name := range args {
+		if err := d.RemoveVolume(name, forceRemove); err != nil {
+			fmt.Fprintf(cmd.OutOrStderr(), "Error: %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), name)
+	}
+	if failed {
+		return fmt.Errorf("some volumes could not be removed")
+	}
+	return nil
+}
+
+func volumePrune(cmd *cobra.Command, args []string) error {
+	d, err := adminDriver(true)
+	if err != nil {
+		return err
+	}
+	removed, err := d.Prune()
+	for _, name := range removed {
+		fmt.Fprintln(cmd.OutOrStdout(), name)
+	}
+	return err
+}
+
+func volumeUnmount(cmd *cobra.Command, args []string) error {
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	return c.ForceUnmount(args[0])
+}
+
+func volumeRemount(cmd *cobra.Command, args []string) error {
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	return c.Remount(args[0])
+}
+
+func adminReload(cmd *cobra.Command, args []string) error {
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	return c.Reload()
+}
+
+func adminReloadConfig(cmd *cobra.Command, args []string) error {
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	restart, err := c.ReloadConfig()
+	for _, s := range restart {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s changed, a restart is needed to apply it\n", s)
+	}
+	return err
+}
+
+func adminReconcile(cmd *cobra.Command, args []string) error {
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	actions, err := c.Reconcile()
+	for _, a := range actions {
+		fmt.Fprintln(cmd.OutOrStdout(), a)
+	}
+	return err
+}
+
+func adminGoroutines(cmd *cobra.Command, args []string) error {
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	return c.Goroutines(cmd.OutOrStdout())
+}
+
+func printVolumes(w io.Writer, infos []driver.VolumeInfo) error {
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tVOLURI\tMOUNTPOINT\tCONNECTIONS\tMOUNTED")
+		for _, i := range infos {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%s\n", i.Name, i.VolumeURI, i.Mountpoint, i.Connections, i.MountConnections, strconv.FormatBool(i.Mounted))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown format %s (table or json)", outputFormat)
+}
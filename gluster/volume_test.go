@@ -0,0 +1,37 @@
+package gluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sapk/docker-volume-gluster/gluster/driver"
+)
+
+func TestAdminDriverOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gluster-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(socket, base, cfg string, off bool) {
+		AdminSocket, BaseDir, driver.CfgFolder, offline = socket, base, cfg, off
+	}(AdminSocket, BaseDir, driver.CfgFolder, offline)
+	AdminSocket = filepath.Join(dir, "admin.sock")
+	BaseDir = filepath.Join(dir, "root")
+	driver.CfgFolder = filepath.Join(dir, "cfg")
+	offline = false
+
+	if _, err := adminDriver(false); err != nil {
+		t.Errorf("Expected read only commands to use the persistence file, got %v", err)
+	}
+	if _, err := adminDriver(true); err == nil || !strings.Contains(err.Error(), "--offline") {
+		t.Errorf("Expected changes without daemon to need --offline, got %v", err)
+	}
+	offline = true
+	if _, err := adminDriver(true); err != nil {
+		t.Errorf("Expected --offline to allow changes, got %v", err)
+	}
+}